@@ -0,0 +1,205 @@
+package concordance
+
+import (
+	"bufio"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type Acronym struct {
+	Acronym    string
+	Definition string
+	// Uses of the acronym anywhere in the text, including where it is defined
+	Count int
+	// Uses of the full definition, compared case insensitively
+	DefinitionCount int
+}
+
+// Returns the number of times the concept was mentioned by either name. The
+// defining mention uses both names but only counts once
+func (a Acronym) MergedCount() int {
+	return a.Count + a.DefinitionCount - 1
+}
+
+// The most tokens a parenthesised group may span beyond its opening token
+const maxParenTokens = 10
+
+// Finds acronyms defined in the text as "World Health Organization (WHO)" or
+// "WHO (World Health Organization)" and counts how often each acronym and its
+// definition are used. Definitions are matched to acronyms the way Schwartz
+// and Hearst describe: every letter of the acronym must appear in order in
+// the definition, the first one at the start of a word. Only the first
+// definition found for an acronym is kept
+func FindAcronyms(scanner *bufio.Scanner) []Acronym {
+	scanner.Split(bufio.ScanWords)
+	tokens := make([]string, 0, 4096)
+	for scanner.Scan() {
+		tokens = append(tokens, scanner.Text())
+	}
+
+	found := make(map[string]*Acronym)
+	order := make([]string, 0)
+	for i := 0; i < len(tokens); i++ {
+		if !strings.HasPrefix(tokens[i], "(") {
+			continue
+		}
+		// Gather the parenthesised group, which may span several tokens. Give
+		// up after a few so unclosed brackets do not scan the rest of the text
+		end := i
+		for end < len(tokens) && end-i <= maxParenTokens && !strings.Contains(strings.TrimRight(tokens[end], ".,;:"), ")") {
+			end++
+		}
+		if end == len(tokens) || end-i > maxParenTokens {
+			continue
+		}
+		inner := strings.Join(tokens[i:end+1], " ")
+		inner = strings.TrimSpace(inner[1:strings.LastIndex(inner, ")")])
+
+		var acronym, definition string
+		if end == i && looksLikeAcronym(inner) {
+			// Definition (ACRONYM)
+			start := i - maxDefinitionWords(inner)
+			if start < 0 {
+				start = 0
+			}
+			cand := scrubPhrase(tokens[start:i])
+			if def, ok := matchDefinition(inner, cand); ok {
+				acronym, definition = inner, def
+			}
+		} else if i > 0 && looksLikeAcronym(ScrubWord(tokens[i-1])) {
+			// ACRONYM (Definition)
+			acr := ScrubWord(tokens[i-1])
+			words := strings.Fields(inner)
+			if len(words) <= maxDefinitionWords(acr) {
+				if def, ok := matchDefinition(acr, scrubPhrase(words)); ok && def == scrubPhrase(words) {
+					acronym, definition = acr, def
+				}
+			}
+		}
+		if acronym != "" && found[acronym] == nil {
+			found[acronym] = &Acronym{Acronym: acronym, Definition: definition}
+			order = append(order, acronym)
+		}
+		i = end
+	}
+
+	acronyms := make([]Acronym, 0, len(order))
+	if len(order) == 0 {
+		return acronyms
+	}
+	// Acronyms are counted exactly, definitions without regard to case
+	definitions := make([]string, 0, len(order))
+	for _, a := range order {
+		definitions = append(definitions, found[a].Definition)
+	}
+	defCounts := countTermsIn(tokens, definitions, false)
+	for _, tok := range tokens {
+		if a, ok := found[ScrubWord(tok)]; ok {
+			a.Count++
+		}
+	}
+	for _, a := range order {
+		found[a].DefinitionCount = defCounts[found[a].Definition]
+		acronyms = append(acronyms, *found[a])
+	}
+	sort.SliceStable(acronyms, func(i, j int) bool {
+		return acronyms[i].MergedCount() > acronyms[j].MergedCount()
+	})
+	return acronyms
+}
+
+// Returns counts with each acronym's count increased by the number of times
+// its definition was used outside the defining mention, so both forms count
+// as mentions of the acronym. caseSensitive must match how counts was built.
+// counts is not modified
+func MergeAcronyms(counts map[string]int, acronyms []Acronym, caseSensitive bool) map[string]int {
+	merged := make(map[string]int, len(counts))
+	for k, v := range counts {
+		merged[k] = v
+	}
+	for _, a := range acronyms {
+		merged[normalizeToken(a.Acronym, caseSensitive)] += a.DefinitionCount - 1
+	}
+	return merged
+}
+
+// Returns true if s is 2 to 10 characters, starts with a letter and is mostly
+// capital letters, like "WHO", "mRNA" or "HTTP2"
+func looksLikeAcronym(s string) bool {
+	if len(s) < 2 || len(s) > 10 || !alphaChar(s[0]) {
+		return false
+	}
+	upper, letters := 0, 0
+	for _, r := range s {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+			letters++
+		case unicode.IsLetter(r):
+			letters++
+		case !unicode.IsDigit(r) && r != '-' && r != '&':
+			return false
+		}
+	}
+	return upper >= 2 && upper*2 >= letters
+}
+
+// The longest definition considered for an acronym, in words
+func maxDefinitionWords(acronym string) int {
+	n := len(acronym)
+	if n+5 < 2*n {
+		return n + 5
+	}
+	return 2 * n
+}
+
+// Joins the scrubbed forms of the tokens with single spaces
+func scrubPhrase(tokens []string) string {
+	words := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if w := ScrubWord(t); w != "" {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Finds the shortest tail of candidate that contains the acronym's letters in
+// order, with the first letter at the start of a word
+func matchDefinition(acronym, candidate string) (string, bool) {
+	a := strings.ToLower(acronym)
+	c := strings.ToLower(candidate)
+	// Indexes into c are used on candidate below
+	if len(c) != len(candidate) {
+		return "", false
+	}
+	ai := len(a) - 1
+	ci := len(c) - 1
+	for ai >= 0 {
+		ch := a[ai]
+		if !alphaChar(ch) && !inRange(ch, '0', '9') {
+			ai--
+			continue
+		}
+		for ci >= 0 && (c[ci] != ch || (ai == 0 && ci > 0 && c[ci-1] != ' ')) {
+			ci--
+		}
+		if ci < 0 {
+			return "", false
+		}
+		ci--
+		ai--
+	}
+	start := strings.LastIndex(candidate[:ci+1], " ") + 1
+	def := candidate[start:]
+	if strings.EqualFold(def, acronym) || len(strings.Fields(def)) < 2 {
+		return "", false
+	}
+	return def, true
+}
+
+// Counts word sequences the same way CountTerms does over tokens already read
+func countTermsIn(tokens []string, terms []string, caseSensitive bool) map[string]int {
+	return CountTerms(bufio.NewScanner(strings.NewReader(strings.Join(tokens, " "))), terms, caseSensitive)
+}
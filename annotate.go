@@ -0,0 +1,162 @@
+package concordance
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The columns every annotation sheet starts with. Any other column holds a
+// category of annotation
+var annotationColumns = []string{"id", "doc", "position", "left", "keyword", "right"}
+
+// Writes the hits as a sheet for manual annotation, as CSV or, when comma is
+// '\t', TSV. Each of the categories gets an empty column for annotators to
+// fill in, "label" if none are given. Labels already on the hits are written
+// out, so a sheet can be exported, annotated and re-exported
+func WriteAnnotationSheet(w io.Writer, hits []KWICHit, comma rune, categories ...string) error {
+	if len(categories) == 0 {
+		categories = []string{"label"}
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	cw.Write(append(append([]string(nil), annotationColumns...), categories...))
+	for _, h := range hits {
+		row := []string{
+			h.ID(),
+			strconv.Itoa(h.Doc),
+			strconv.Itoa(h.Position),
+			spreadsheetText(h.Left),
+			spreadsheetText(h.Keyword),
+			spreadsheetText(h.Right),
+		}
+		for _, c := range categories {
+			row = append(row, h.Labels[c])
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Quotes text that a spreadsheet would otherwise run as a formula, such as
+// "-- the" or "=SUM(A1)", by starting it with an apostrophe. Only the context
+// columns are quoted, since ReadAnnotations never reads them back
+func spreadsheetText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// Reads an annotated sheet written by WriteAnnotationSheet and returns the
+// labels in it by hit ID and then category. Columns are found by their header,
+// so annotators may reorder them or add new categories. Empty cells are
+// skipped
+func ReadAnnotations(r io.Reader, comma rune) (map[string]map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	idCol := -1
+	categories := make(map[int]string)
+	for i, name := range header {
+		// Spreadsheets often save a byte order mark at the start of the file
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if name == "id" {
+			idCol = i
+		} else if !isAnnotationColumn(name) && name != "" {
+			categories[i] = name
+		}
+	}
+	if idCol < 0 {
+		return nil, fmt.Errorf("concordance: annotation sheet has no id column")
+	}
+
+	labels := make(map[string]map[string]string)
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return labels, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if idCol >= len(row) {
+			continue
+		}
+		id := strings.TrimSpace(row[idCol])
+		for i, category := range categories {
+			if i >= len(row) {
+				continue
+			}
+			if value := strings.TrimSpace(row[i]); value != "" {
+				if labels[id] == nil {
+					labels[id] = make(map[string]string)
+				}
+				labels[id][category] = value
+			}
+		}
+	}
+}
+
+func isAnnotationColumn(name string) bool {
+	for _, c := range annotationColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Attaches labels read with ReadAnnotations to the hits with matching IDs and
+// returns how many hits were labeled
+func ApplyAnnotations(hits []KWICHit, labels map[string]map[string]string) int {
+	n := 0
+	for i := range hits {
+		l, ok := labels[hits[i].ID()]
+		if !ok {
+			continue
+		}
+		if hits[i].Labels == nil {
+			hits[i].Labels = make(map[string]string, len(l))
+		}
+		for c, v := range l {
+			hits[i].Labels[c] = v
+		}
+		n++
+	}
+	return n
+}
+
+// Counts the hits by their label in one annotation category. Hits without a
+// label in that category are counted under the empty string
+func LabelCounts(hits []KWICHit, category string) map[string]int {
+	counts := make(map[string]int)
+	for _, h := range hits {
+		counts[h.Labels[category]]++
+	}
+	return counts
+}
+
+// Returns the annotation categories used on any of the hits, sorted
+func LabelCategories(hits []KWICHit) []string {
+	seen := make(map[string]bool)
+	categories := make([]string, 0)
+	for _, h := range hits {
+		for c := range h.Labels {
+			if !seen[c] {
+				seen[c] = true
+				categories = append(categories, c)
+			}
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
@@ -0,0 +1,138 @@
+package concordance
+
+import (
+	"sort"
+	"strings"
+)
+
+// The structural classes of Biber's lexical bundles
+type BundleStructure int
+
+const (
+	OtherBundle BundleStructure = iota
+	// Noun phrase fragments such as "the end of the"
+	NPBased
+	// Prepositional phrase fragments such as "in the middle of"
+	PPBased
+	// Verb phrase fragments such as "I don't know what" or "it is important to"
+	VPBased
+	// Dependent clause fragments such as "if you want to"
+	DependentClauseBased
+)
+
+func (s BundleStructure) String() string {
+	switch s {
+	case NPBased:
+		return "NP-based"
+	case PPBased:
+		return "PP-based"
+	case VPBased:
+		return "VP-based"
+	case DependentClauseBased:
+		return "dependent clause"
+	}
+	return "other"
+}
+
+type LexicalBundle struct {
+	Words      string
+	Count      int
+	PerMillion float64
+	// The number of documents the bundle occurs in
+	Range     int
+	Structure BundleStructure
+}
+
+// Word lists used to classify bundles by their opening words. Both spellings of
+// contractions are listed since scrubbing may drop the apostrophe
+var (
+	bundleSubordinators = wordSet("if when what that because whether which who whom whose how where why although though since unless while as until before after")
+	bundlePrepositions  = wordSet("in of on at for from with by to into about as through over under between during without within among across against towards toward upon")
+	bundleDeterminers   = wordSet("the a an this that these those one some any each every all most many much such no another other my your his her its our their both either neither")
+	bundlePronouns      = wordSet("i you he she it we they there this that what")
+	bundleVerbs         = wordSet("is are was were be been being am 's 're have has had having do does did don't dont doesn't doesnt didn't didnt can could will would shall should may might must can't cant won't wont going want know think see get got say said take make go let look need seems seem mean")
+)
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// Classifies a bundle by its structure, using word lists in place of a part
+// of speech tagger. Bundles opening with a subordinator are dependent clause
+// fragments, those opening with a preposition are PP-based, those opening with
+// a determiner or holding "of" and no verb are NP-based, and those opening with
+// a pronoun or holding a verb are VP-based
+func ClassifyBundle(words []string) BundleStructure {
+	if len(words) == 0 {
+		return OtherBundle
+	}
+	lower := make([]string, len(words))
+	hasVerb, hasOf := false, false
+	for i, w := range words {
+		lower[i] = strings.ToLower(w)
+		hasVerb = hasVerb || bundleVerbs[lower[i]]
+		hasOf = hasOf || lower[i] == "of"
+	}
+	first := lower[0]
+	switch {
+	// "that" and "as" are also determiners and prepositions, so a following
+	// pronoun decides that they introduce a clause
+	case bundleSubordinators[first] && (first != "that" && first != "as" || len(lower) > 1 && bundlePronouns[lower[1]]):
+		return DependentClauseBased
+	case bundlePrepositions[first]:
+		return PPBased
+	case bundleDeterminers[first] && !hasVerb, hasOf && !hasVerb:
+		return NPBased
+	case bundlePronouns[first], hasVerb:
+		return VPBased
+	}
+	return OtherBundle
+}
+
+// Finds Biber-style lexical bundles: sequences of n words that occur at least
+// minPerMillion times per million words across the corpus and in at least
+// minRange different documents. Bundles are returned most frequent first,
+// each classified with ClassifyBundle
+func (c *Corpus) LexicalBundles(n int, minPerMillion float64, minRange int) []LexicalBundle {
+	counts := make(map[string]int, 4096)
+	ranges := make(map[string]int, 4096)
+	words := 0
+	for _, d := range c.Documents {
+		grams, _ := ngramCount(c.scanner(d), n, c.normalize)
+		for g, count := range grams {
+			counts[g] += count
+			ranges[g]++
+		}
+		_, total := ngramCount(c.scanner(d), 1, c.normalize)
+		words += total
+	}
+
+	bundles := make([]LexicalBundle, 0)
+	if words == 0 {
+		return bundles
+	}
+	for g, count := range counts {
+		pm := float64(count) * 1e6 / float64(words)
+		if pm < minPerMillion || ranges[g] < minRange {
+			continue
+		}
+		bundles = append(bundles, LexicalBundle{
+			Words:      g,
+			Count:      count,
+			PerMillion: pm,
+			Range:      ranges[g],
+			Structure:  ClassifyBundle(strings.Fields(g)),
+		})
+	}
+	sort.Slice(bundles, func(i, j int) bool {
+		if bundles[i].Count != bundles[j].Count {
+			return bundles[i].Count > bundles[j].Count
+		}
+		return bundles[i].Words < bundles[j].Words
+	})
+	return bundles
+}
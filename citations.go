@@ -0,0 +1,174 @@
+package concordance
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Excludes everything from a References, Bibliography or Works Cited heading
+// to the end of the text. Add it to Corpus.Regions, or pass it to
+// FilterRegions, to leave reference lists out of word counts
+var ReferenceSectionRule = RegionRule{Start: referenceHeading}
+
+var (
+	referenceHeading = regexp.MustCompile(`(?im)^[ \t]*(?:\d+\.?[ \t]+)?(?:references|bibliography|works cited|literature cited)[ \t]*:?[ \t]*$`)
+
+	numericCitation = regexp.MustCompile(`\[(\d+(?:\s*[-–,]\s*\d+)*)\]`)
+	// A parenthetical holding at least one year, split on ; into citations
+	parenCitation = regexp.MustCompile(`\(([^()]*\d{4}[a-z]?[^()]*)\)`)
+	authorYear    = regexp.MustCompile(`^(?:see |e\.g\.,? |cf\. )?(\p{Lu}[\p{L}'’-]+(?: et al\.| (?:and|&) \p{Lu}[\p{L}'’-]+)?),? (\d{4}[a-z]?)(?:, pp?\. ?\d+(?:[-–]\d+)?)?$`)
+	// Smith (2020) and Smith et al. (2020)
+	narrativeCitation = regexp.MustCompile(`(\p{Lu}[\p{L}'’-]+(?: et al\.| (?:and|&) \p{Lu}[\p{L}'’-]+)?) \((\d{4}[a-z]?)\)`)
+	numberedReference = regexp.MustCompile(`^\s*\[?(\d+)[\].]\s`)
+)
+
+// Citation ranges larger than this are not expanded
+const maxCitationRange = 100
+
+type Citation struct {
+	// "12" for numeric citations, "Smith 2020" for author-year citations
+	Key   string
+	Style string
+	// Byte offset of the citation in the text
+	Offset int
+}
+
+type CitedReference struct {
+	Key   string
+	Count int
+	// The matching entry of the reference list, if one was found
+	Reference string
+}
+
+type CitationReport struct {
+	Citations  []Citation
+	Counts     map[string]int
+	References []string
+	// The text before the reference list
+	Body string
+}
+
+// Finds in-text citations in both numeric ([12], [3-5]) and author-year
+// ((Smith, 2020), Smith et al. (2020)) styles, and the reference list that
+// follows a References or Bibliography heading. Citations are only looked for
+// in the body, before the reference list
+func FindCitations(text string) *CitationReport {
+	r := &CitationReport{
+		Citations:  make([]Citation, 0),
+		Counts:     make(map[string]int),
+		References: make([]string, 0),
+		Body:       text,
+	}
+	if loc := referenceHeading.FindStringIndex(text); loc != nil {
+		r.Body = text[:loc[0]]
+		for _, line := range strings.Split(text[loc[1]:], "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				r.References = append(r.References, line)
+			}
+		}
+	}
+
+	for _, m := range numericCitation.FindAllStringSubmatchIndex(r.Body, -1) {
+		for _, key := range expandCitationNumbers(r.Body[m[2]:m[3]]) {
+			r.add(key, "numeric", m[0])
+		}
+	}
+	for _, m := range parenCitation.FindAllStringSubmatchIndex(r.Body, -1) {
+		for _, part := range strings.Split(r.Body[m[2]:m[3]], ";") {
+			if sub := authorYear.FindStringSubmatch(strings.TrimSpace(part)); sub != nil {
+				r.add(sub[1]+" "+sub[2], "author-year", m[0])
+			}
+		}
+	}
+	for _, m := range narrativeCitation.FindAllStringSubmatchIndex(r.Body, -1) {
+		r.add(r.Body[m[2]:m[3]]+" "+r.Body[m[4]:m[5]], "author-year", m[0])
+	}
+
+	sort.SliceStable(r.Citations, func(i, j int) bool {
+		return r.Citations[i].Offset < r.Citations[j].Offset
+	})
+	return r
+}
+
+func (r *CitationReport) add(key, style string, offset int) {
+	r.Citations = append(r.Citations, Citation{Key: key, Style: style, Offset: offset})
+	r.Counts[key]++
+}
+
+// Expands "1, 3-5" into 1, 3, 4 and 5
+func expandCitationNumbers(s string) []string {
+	keys := make([]string, 0)
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.FieldsFunc(part, func(r rune) bool {
+			return r == '-' || r == '–' || r == ' '
+		})
+		if len(bounds) == 2 {
+			lo, _ := strconv.Atoi(bounds[0])
+			hi, _ := strconv.Atoi(bounds[1])
+			if lo <= hi && hi-lo <= maxCitationRange {
+				for n := lo; n <= hi; n++ {
+					keys = append(keys, strconv.Itoa(n))
+				}
+				continue
+			}
+		}
+		for _, b := range bounds {
+			keys = append(keys, b)
+		}
+	}
+	return keys
+}
+
+// Returns up to n of the most cited keys, matched to their reference list
+// entries where possible. Numeric keys match entries numbered "[12]" or "12.",
+// author-year keys match entries starting with the author's name and
+// containing the year. A value <= 0 returns them all
+func (r *CitationReport) MostCited(n int) []CitedReference {
+	cited := make([]CitedReference, 0, len(r.Counts))
+	for key, count := range r.Counts {
+		cited = append(cited, CitedReference{Key: key, Count: count, Reference: r.reference(key)})
+	}
+	sort.Slice(cited, func(i, j int) bool {
+		if cited[i].Count != cited[j].Count {
+			return cited[i].Count > cited[j].Count
+		}
+		return cited[i].Key < cited[j].Key
+	})
+	if n > 0 && n < len(cited) {
+		cited = cited[:n]
+	}
+	return cited
+}
+
+// Finds the reference list entry for a citation key
+func (r *CitationReport) reference(key string) string {
+	if _, err := strconv.Atoi(key); err == nil {
+		for _, ref := range r.References {
+			if m := numberedReference.FindStringSubmatch(ref); m != nil && m[1] == key {
+				return ref
+			}
+		}
+		return ""
+	}
+	i := strings.LastIndex(key, " ")
+	author := strings.Fields(key)[0]
+	year := key[i+1:]
+	for _, ref := range r.References {
+		if strings.HasPrefix(ref, author) && strings.Contains(ref, year) {
+			return ref
+		}
+	}
+	return ""
+}
+
+// Runs FindCitations over every document in the corpus, ignoring the corpus's
+// region rules since those usually remove the reference list
+func (c *Corpus) Citations() []*CitationReport {
+	reports := make([]*CitationReport, len(c.Documents))
+	for i, d := range c.Documents {
+		reports[i] = FindCitations(d.Text)
+	}
+	return reports
+}
@@ -0,0 +1,491 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/odysseus/concordance"
+)
+
+// Browses a frozen snapshot in a full screen terminal view. The files, if any,
+// are loaded as a corpus for the KWIC and collocate panes; without them only
+// the word list and histogram are shown. When the snapshot does not exist yet
+// it is built from the files and saved
+func runBrowse(args []string) error {
+	flags := flag.NewFlagSet("browse", flag.ExitOnError)
+	snapshot := flags.String("snapshot", "", "frozen snapshot to browse, created from the files if missing")
+	span := flags.Int("span", 6, "words of context on each side of KWIC lines")
+	caseSensitive := flags.Bool("case", false, "count differently cased words separately")
+	flags.Parse(args)
+
+	var corpus *concordance.Corpus
+	// Counted once up front, rather than each time the selection moves
+	var counts *concordance.Concordance
+	if flags.NArg() > 0 {
+		corpus = concordance.NewCorpus(*caseSensitive)
+		for _, name := range flags.Args() {
+			if _, err := corpus.AddFile(name); err != nil {
+				return err
+			}
+		}
+		counts = corpus.Concordance(0)
+	}
+
+	var frozen *concordance.Frozen
+	if *snapshot != "" {
+		f, err := os.Open(*snapshot)
+		switch {
+		case err == nil:
+			frozen, err = concordance.LoadFrozen(f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		case !os.IsNotExist(err) || corpus == nil:
+			return err
+		}
+	}
+	if frozen == nil {
+		if corpus == nil {
+			return errors.New("nothing to browse: give a -snapshot or input files")
+		}
+		frozen = counts.Freeze()
+		if *snapshot != "" {
+			if err := saveSnapshot(*snapshot, frozen); err != nil {
+				return err
+			}
+		}
+	}
+
+	restore, err := rawTerminal()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	b := &browser{
+		frozen:        frozen,
+		corpus:        corpus,
+		span:          *span,
+		caseSensitive: *caseSensitive,
+		cache:         make(map[string]browsed),
+	}
+	if counts != nil {
+		b.counts = counts.Counts
+	}
+	key := make([]byte, 16)
+	for {
+		b.height, b.width = terminalSize()
+		os.Stdout.WriteString(b.render())
+		n, err := os.Stdin.Read(key)
+		if err != nil {
+			return err
+		}
+		if b.handle(keyName(key[:n])) {
+			return nil
+		}
+	}
+}
+
+func saveSnapshot(name string, f *concordance.Frozen) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Switches the terminal to raw mode on the alternate screen. The returned
+// function puts it back as it was
+func rawTerminal() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, errors.New("standard input is not a terminal")
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	os.Stdout.WriteString("\x1b[?1049h\x1b[?25l")
+	return func() {
+		os.Stdout.WriteString("\x1b[?25h\x1b[?1049l")
+		stty(strings.TrimSpace(saved))
+	}, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// Returns the rows and columns of the terminal, or 24 by 80 if they cannot be
+// found
+func terminalSize() (int, int) {
+	out, err := stty("size")
+	if err == nil {
+		if f := strings.Fields(out); len(f) == 2 {
+			rows, err1 := strconv.Atoi(f[0])
+			cols, err2 := strconv.Atoi(f[1])
+			if err1 == nil && err2 == nil && rows > 0 && cols > 0 {
+				return rows, cols
+			}
+		}
+	}
+	return 24, 80
+}
+
+// Names the key read from the terminal. Printable characters are returned as
+// they are
+func keyName(b []byte) string {
+	switch string(b) {
+	case "\x1b[A", "\x1bOA":
+		return "up"
+	case "\x1b[B", "\x1bOB":
+		return "down"
+	case "\x1b[5~":
+		return "pgup"
+	case "\x1b[6~":
+		return "pgdn"
+	case "\x1b[H", "\x1b[1~", "\x1bOH":
+		return "home"
+	case "\x1b[F", "\x1b[4~", "\x1bOF":
+		return "end"
+	case "\x1b":
+		return "esc"
+	case "\t":
+		return "tab"
+	case "\r", "\n":
+		return "enter"
+	case "\x7f", "\b":
+		return "backspace"
+	case "\x03":
+		return "ctrl-c"
+	}
+	return string(b)
+}
+
+// Which pane the movement keys scroll
+const (
+	focusWords = iota
+	focusKWIC
+)
+
+// The state of the terminal browser, kept apart from the terminal itself
+type browser struct {
+	frozen *concordance.Frozen
+	// nil when browsing a snapshot alone
+	corpus *concordance.Corpus
+	span   int
+	// Whether the snapshot was counted case sensitively, from the -case flag
+	caseSensitive bool
+
+	height, width int
+	// Index into the word list by rank, and the first visible row
+	selected, top int
+	focus         int
+	kwicTop       int
+	searching     bool
+	query         string
+	message       string
+
+	// Word counts of the corpus for scoring collocates
+	counts map[string]int
+	// The word the KWIC hits and collocates were found for
+	loaded     string
+	hits       []concordance.KWICHit
+	collocates []concordance.Collocate
+	// Results for words already visited, so moving back to them is instant
+	cache map[string]browsed
+}
+
+type browsed struct {
+	hits       []concordance.KWICHit
+	collocates []concordance.Collocate
+}
+
+// Applies a key press and reports whether the browser should quit
+func (b *browser) handle(key string) bool {
+	if b.searching {
+		switch key {
+		case "enter":
+			b.searching = false
+			b.search(b.query)
+		case "esc", "ctrl-c":
+			b.searching = false
+		case "backspace":
+			if _, size := utf8.DecodeLastRuneInString(b.query); size > 0 {
+				b.query = b.query[:len(b.query)-size]
+			}
+		default:
+			if utf8.RuneCountInString(key) == 1 && key >= " " {
+				b.query += key
+			}
+		}
+		return false
+	}
+
+	b.message = ""
+	page := b.listHeight()
+	switch key {
+	case "q", "ctrl-c":
+		return true
+	case "tab":
+		b.focus = 1 - b.focus
+	case "/":
+		b.searching = true
+		b.query = ""
+	case "up", "k":
+		b.move(-1)
+	case "down", "j":
+		b.move(1)
+	case "pgup":
+		b.move(-page)
+	case "pgdn", " ":
+		b.move(page)
+	case "home", "g":
+		b.move(-b.frozen.Len() - len(b.hits))
+	case "end", "G":
+		b.move(b.frozen.Len() + len(b.hits))
+	}
+	return false
+}
+
+// Moves the selection, or scrolls the KWIC lines, by delta rows
+func (b *browser) move(delta int) {
+	if b.focus == focusKWIC {
+		b.kwicTop = clamp(b.kwicTop+delta, 0, len(b.hits)-1)
+		return
+	}
+	b.selected = clamp(b.selected+delta, 0, b.frozen.Len()-1)
+}
+
+// Selects word in the list
+func (b *browser) search(word string) {
+	word = strings.TrimSpace(word)
+	if !b.caseSensitive {
+		word = strings.ToLower(word)
+	}
+	rank, ok := b.frozen.Rank(concordance.ScrubWord(word))
+	if !ok {
+		b.message = fmt.Sprintf("%q is not in the snapshot", word)
+		return
+	}
+	b.selected = rank - 1
+	b.focus = focusWords
+}
+
+// Finds the KWIC hits and collocates of the selected word if they are not
+// already loaded
+func (b *browser) load() {
+	if b.corpus == nil || b.frozen.Len() == 0 {
+		return
+	}
+	word := b.frozen.AtRank(b.selected + 1).Word
+	if word == b.loaded {
+		return
+	}
+	b.loaded = word
+	b.kwicTop = 0
+	if r, ok := b.cache[word]; ok {
+		b.hits, b.collocates = r.hits, r.collocates
+		return
+	}
+	b.hits = b.corpus.KWIC(word, b.span)
+	b.collocates = b.corpus.CollocatesWithCounts(word, 4, 2, b.counts)
+	b.cache[word] = browsed{b.hits, b.collocates}
+}
+
+// Rows available to the word list, between the title and status lines
+func (b *browser) listHeight() int {
+	return max(b.height-3, 1)
+}
+
+// Draws the whole screen. The word list is on the left and the KWIC lines,
+// collocates and length histogram are stacked on the right
+func (b *browser) render() string {
+	b.load()
+	leftWidth := min(32, b.width/3)
+	rightWidth := max(b.width-leftWidth-1, 0)
+	body := max(b.height-2, 0)
+
+	left := b.wordPane(leftWidth, body)
+	kwicRows := body / 2
+	colRows := (body - kwicRows) / 2
+	right := append(b.kwicPane(rightWidth, kwicRows), b.collocatePane(rightWidth, colRows)...)
+	right = append(right, b.histogramPane(rightWidth, body-kwicRows-colRows)...)
+
+	var s strings.Builder
+	s.WriteString("\x1b[H")
+	title := fmt.Sprintf(" concordance  %d words, %d unique", b.frozen.Total, b.frozen.Len())
+	fmt.Fprintf(&s, "\x1b[7m%s\x1b[0m\r\n", fit(title, b.width))
+	for i := 0; i < body; i++ {
+		s.WriteString(left[i])
+		s.WriteString("│")
+		s.WriteString(right[i])
+		s.WriteString("\x1b[K\r\n")
+	}
+	status := " ↑↓ move  PgUp/PgDn page  Tab switch pane  / find  q quit"
+	if b.searching {
+		status = " find: " + b.query
+	} else if b.message != "" {
+		status = " " + b.message
+	}
+	fmt.Fprintf(&s, "\x1b[7m%s\x1b[0m", fit(status, b.width))
+	return s.String()
+}
+
+func (b *browser) wordPane(width, rows int) []string {
+	lines := make([]string, rows)
+	if rows == 0 {
+		return lines
+	}
+	lines[0] = bold(fit(" Rank  Word", width))
+	list := rows - 1
+	// Keep the selection on screen
+	if b.selected < b.top {
+		b.top = b.selected
+	} else if b.selected >= b.top+list {
+		b.top = b.selected - list + 1
+	}
+	for i := 1; i < rows; i++ {
+		rank := b.top + i
+		if rank > b.frozen.Len() {
+			lines[i] = fit("", width)
+			continue
+		}
+		t := b.frozen.AtRank(rank)
+		count := strconv.Itoa(t.Count)
+		word := fit(fmt.Sprintf(" %5d %s", rank, t.Word), max(width-len(count)-1, 0))
+		line := fit(word+" "+count, width)
+		if rank-1 == b.selected {
+			style := "\x1b[7m"
+			if b.focus != focusWords {
+				style = "\x1b[4m"
+			}
+			line = style + line + "\x1b[0m"
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+func (b *browser) kwicPane(width, rows int) []string {
+	lines := make([]string, rows)
+	if rows == 0 {
+		return lines
+	}
+	header := " KWIC"
+	if b.corpus == nil {
+		header += " (give input files to see KWIC lines and collocates)"
+	} else {
+		header += fmt.Sprintf(" %q  %d hits", b.loaded, len(b.hits))
+	}
+	if b.focus == focusKWIC {
+		header += "  [scrolling]"
+	}
+	lines[0] = bold(fit(header, width))
+	side := max((width-2)/2-6, 0)
+	for i := 1; i < rows; i++ {
+		n := b.kwicTop + i - 1
+		if n >= len(b.hits) {
+			lines[i] = fit("", width)
+			continue
+		}
+		h := b.hits[n]
+		kw := h.Keyword
+		leftCtx := fitLeft(h.Left, side)
+		room := max(width-side-utf8.RuneCountInString(kw)-3, 0)
+		lines[i] = leftCtx + " \x1b[1;33m" + kw + "\x1b[0m " + fit(h.Right, room) + " "
+	}
+	return lines
+}
+
+func (b *browser) collocatePane(width, rows int) []string {
+	lines := make([]string, rows)
+	if rows == 0 {
+		return lines
+	}
+	lines[0] = bold(fit(" Collocates  logDice  count", width))
+	for i := 1; i < rows; i++ {
+		if i-1 >= len(b.collocates) {
+			lines[i] = fit("", width)
+			continue
+		}
+		col := b.collocates[i-1]
+		lines[i] = fit(fmt.Sprintf(" %-20s %6.2f %6d", col.Word, col.LogDice, col.Count), width)
+	}
+	return lines
+}
+
+// Draws the word length histogram as horizontal bars, one per length that
+// occurs, scaled to the widest bar
+func (b *browser) histogramPane(width, rows int) []string {
+	lines := make([]string, rows)
+	if rows == 0 {
+		return lines
+	}
+	lines[0] = bold(fit(" Word lengths", width))
+	type bar struct{ length, count int }
+	bars := make([]bar, 0)
+	most := 0
+	for length, count := range b.frozen.LengthHistogram {
+		if count > 0 {
+			bars = append(bars, bar{length, count})
+			most = max(most, count)
+		}
+	}
+	barWidth := max(width-16, 0)
+	for i := 1; i < rows; i++ {
+		if i-1 >= len(bars) {
+			lines[i] = fit("", width)
+			continue
+		}
+		br := bars[i-1]
+		n := br.count * barWidth / most
+		lines[i] = fit(fmt.Sprintf(" %3d %s %d", br.length, strings.Repeat("█", n), br.count), width)
+	}
+	return lines
+}
+
+func bold(s string) string {
+	return "\x1b[1m" + s + "\x1b[0m"
+}
+
+// Cuts s to width runes, padding it with spaces if it is shorter
+func fit(s string, width int) string {
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:width])
+	}
+	return s + strings.Repeat(" ", width-len(r))
+}
+
+// Like fit but keeps the end of s, padding on the left
+func fitLeft(s string, width int) string {
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[len(r)-width:])
+	}
+	return strings.Repeat(" ", width-len(r)) + s
+}
+
+func clamp(n, lo, hi int) int {
+	if n > hi {
+		n = hi
+	}
+	if n < lo {
+		n = lo
+	}
+	return n
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/odysseus/concordance"
+)
+
+// Suitable for go:generate, e.g.
+//
+//	//go:generate concordance gen -pkg stopwords -name Words -kind hash -top 200 -o words_gen.go corpus.txt
+func runGen(args []string) error {
+	flags := flag.NewFlagSet("gen", flag.ExitOnError)
+	pkg := flags.String("pkg", "", "package name of the generated file (defaults to $GOPACKAGE)")
+	name := flags.String("name", "Words", "identifier of the generated variable or function")
+	kind := flags.String("kind", "slice", "what to generate: slice, map or hash")
+	top := flags.Int("top", 0, "number of most used words to include, 0 for all")
+	caseSensitive := flags.Bool("case", false, "count differently cased words separately")
+	script := flags.String("script", "", "normalize arabic or hebrew words before counting")
+	out := flags.String("o", "", "output file (defaults to standard output)")
+	flags.Parse(args)
+
+	if *pkg == "" {
+		*pkg = os.Getenv("GOPACKAGE")
+	}
+	if *pkg == "" {
+		return fmt.Errorf("no package name given with -pkg or $GOPACKAGE")
+	}
+	kinds := map[string]concordance.GoOutput{
+		"slice": concordance.GoSlice,
+		"map":   concordance.GoMap,
+		"hash":  concordance.GoPerfectHash,
+	}
+	output, ok := kinds[*kind]
+	if !ok {
+		return fmt.Errorf("unknown -kind %q", *kind)
+	}
+
+	filters, err := scriptFilters(*script)
+	if err != nil {
+		return err
+	}
+
+	scanner, closeInputs, err := openInputs(flags.Args())
+	if err != nil {
+		return err
+	}
+	var counts *concordance.Concordance
+	if filters != nil {
+		counts = concordance.NewConcordanceFiltered(scanner, 0, filters...)
+	} else {
+		counts = concordance.NewConcordance(scanner, *caseSensitive, 0)
+	}
+	// Ranking through a frozen snapshot breaks ties by word, so the same input
+	// always generates the same file
+	words := counts.Freeze().TopWords(*top)
+	closeInputs()
+
+	var b bytes.Buffer
+	if err := concordance.GenerateGo(&b, *pkg, *name, words, output); err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(b.Bytes())
+		return err
+	}
+	return os.WriteFile(*out, b.Bytes(), 0644)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/odysseus/concordance"
+)
+
+// Returns the filters for counting text in the named script, arabic or
+// hebrew, or nil for the usual scrubbing and case folding when name is empty
+func scriptFilters(name string) ([]concordance.Filter, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "arabic":
+		return concordance.ArabicFilters(false), nil
+	case "hebrew":
+		return concordance.HebrewFilters(false), nil
+	}
+	return nil, fmt.Errorf("unknown -script %q", name)
+}
+
+// Adds the named files to a corpus, or standard input if there are none
+func loadCorpus(names []string, caseSensitive bool, script string) (*concordance.Corpus, error) {
+	filters, err := scriptFilters(script)
+	if err != nil {
+		return nil, err
+	}
+	c := concordance.NewCorpus(caseSensitive)
+	c.Filters = filters
+	if len(names) == 0 {
+		_, err := c.AddFormatted("-", "", os.Stdin)
+		return c, err
+	}
+	for _, name := range names {
+		if _, err := c.AddFile(name); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Prints every occurrence of a word with its context, one hit per line
+func runKWIC(args []string) error {
+	flags := flag.NewFlagSet("kwic", flag.ExitOnError)
+	q := &concordance.KWICQuery{}
+	order := flags.String("sort", "position", "order of the hits: position, left or right")
+	flags.IntVar(&q.Span, "span", 5, "words of context on each side")
+	flags.IntVar(&q.Offset, "offset", 0, "number of hits to skip")
+	flags.IntVar(&q.Limit, "limit", 0, "number of hits to print, 0 for all")
+	flags.IntVar(&q.Sample, "sample", 0, "pick this many hits at random")
+	flags.Int64Var(&q.Seed, "seed", 1, "random seed for -sample")
+	flags.IntVar(&q.Thin, "thin", 0, "keep every nth hit")
+	flags.IntVar(&q.PerDocument, "per-doc", 0, "keep at most this many hits from each document")
+	caseSensitive := flags.Bool("case", false, "match case")
+	script := flags.String("script", "", "normalize arabic or hebrew words before matching")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return errors.New("usage: concordance kwic [flags] word [files]")
+	}
+	var err error
+	if q.Order, err = concordance.ParseKWICOrder(*order); err != nil {
+		return err
+	}
+	q.Word = flags.Arg(0)
+
+	c, err := loadCorpus(flags.Args()[1:], *caseSensitive, *script)
+	if err != nil {
+		return err
+	}
+	hits, total := c.QueryKWIC(*q)
+	out := bufio.NewWriter(os.Stdout)
+	for _, h := range hits {
+		fmt.Fprintf(out, "%s\t%s\n", c.Documents[h.Doc].Name, h.String())
+	}
+	if len(hits) < total {
+		fmt.Fprintf(os.Stderr, "showing %d of %d hits\n", len(hits), total)
+	}
+	return out.Flush()
+}
+
+// Serves KWIC searches over the files as JSON at /kwic
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	caseSensitive := flags.Bool("case", false, "match case")
+	script := flags.String("script", "", "normalize arabic or hebrew words before matching")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return errors.New("usage: concordance serve [flags] files")
+	}
+	c, err := loadCorpus(flags.Args(), *caseSensitive, *script)
+	if err != nil {
+		return err
+	}
+	http.Handle("/kwic", concordance.KWICHandler(c))
+	fmt.Fprintf(os.Stderr, "serving %d documents on http://%s/kwic\n", len(c.Documents), *addr)
+	return http.ListenAndServe(*addr, nil)
+}
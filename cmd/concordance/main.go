@@ -0,0 +1,90 @@
+// Command concordance runs the concordance library from the command line.
+//
+// Usage:
+//
+//	concordance <command> [flags] [files]
+//
+// The commands are:
+//
+//	gen    write the most used words of the input as a Go source file
+//	browse explore a snapshot in a full screen terminal view
+//	wc     print line, word and byte counts like wc(1)
+//	kwic   print every occurrence of a word in its context
+//	serve  serve KWIC searches over HTTP
+//
+// Input is read from the named files, or standard input if there are none.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"gen", "write the most used words of the input as a Go source file", runGen},
+	{"browse", "explore a snapshot in a full screen terminal view", runBrowse},
+	{"wc", "print line, word and byte counts like wc(1)", runWC},
+	{"kwic", "print every occurrence of a word in its context", runKWIC},
+	{"serve", "serve KWIC searches over HTTP", runServe},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "concordance %s: %v\n", c.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: concordance <command> [flags] [files]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.usage)
+	}
+}
+
+// Returns a scanner over the concatenation of the named files, or standard
+// input if there are none, along with a function closing the files
+func openInputs(names []string) (*bufio.Scanner, func(), error) {
+	if len(names) == 0 {
+		return bufio.NewScanner(os.Stdin), func() {}, nil
+	}
+	files := make([]*os.File, 0, len(names))
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	readers := make([]io.Reader, 0, len(names)*2)
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		files = append(files, f)
+		// Keep the last word of one file from running into the next
+		readers = append(readers, f, strings.NewReader("\n"))
+	}
+	return bufio.NewScanner(io.MultiReader(readers...)), closeAll, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/odysseus/concordance"
+)
+
+// A line kept for the -longest report
+type longLine struct {
+	name  string
+	line  int
+	words int
+	text  string
+}
+
+// Prints counts in the layout of wc(1), counting words with ScrubWord so the
+// numbers agree with the rest of the library. With no column flags it prints
+// lines, words and bytes, like wc
+func runWC(args []string) error {
+	flags := flag.NewFlagSet("wc", flag.ExitOnError)
+	lines := flags.Bool("l", false, "print the line count")
+	words := flags.Bool("w", false, "print the word count")
+	bytes := flags.Bool("c", false, "print the byte count")
+	chars := flags.Bool("m", false, "print the character count")
+	perLine := flags.Bool("per-line", false, "print the word count of every line")
+	longest := flags.Int("longest", 0, "print the n lines with the most words")
+	flags.Parse(args)
+	if !*lines && !*words && !*bytes && !*chars {
+		*lines, *words, *bytes = true, true, true
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	row := func(c concordance.WCCounts, name string) {
+		// Same column order as wc
+		for _, col := range []struct {
+			show bool
+			n    int
+		}{{*lines, c.Lines}, {*words, c.Words}, {*chars, c.Chars}, {*bytes, c.Bytes}} {
+			if col.show {
+				fmt.Fprintf(out, " %7d", col.n)
+			}
+		}
+		if name != "" {
+			fmt.Fprintf(out, " %s", name)
+		}
+		fmt.Fprintln(out)
+	}
+
+	top := make([]longLine, 0, *longest+1)
+	count := func(name string, r io.Reader) (concordance.WCCounts, error) {
+		return concordance.WordCountLines(r, func(line, n int, text string) {
+			if *perLine {
+				fmt.Fprintf(out, " %7d %s:%d\n", n, displayName(name), line)
+			}
+			if *longest > 0 && (len(top) < *longest || n > top[len(top)-1].words) {
+				// Keep the longest lines sorted, earlier lines first on ties
+				i := sort.Search(len(top), func(i int) bool { return top[i].words < n })
+				top = append(top, longLine{})
+				copy(top[i+1:], top[i:])
+				top[i] = longLine{name, line, n, text}
+				if len(top) > *longest {
+					top = top[:*longest]
+				}
+			}
+		})
+	}
+
+	names := flags.Args()
+	if len(names) == 0 {
+		c, err := count("", os.Stdin)
+		if err != nil {
+			return err
+		}
+		row(c, "")
+	}
+	var total concordance.WCCounts
+	failed := 0
+	for _, name := range names {
+		// Like wc, - names standard input
+		f := os.Stdin
+		if name != "-" {
+			var err error
+			if f, err = os.Open(name); err != nil {
+				// Keep going like wc, reporting the failure at the end
+				fmt.Fprintf(os.Stderr, "concordance wc: %v\n", err)
+				failed++
+				continue
+			}
+		}
+		c, err := count(name, f)
+		if f != os.Stdin {
+			f.Close()
+		}
+		if err != nil {
+			return err
+		}
+		row(c, name)
+		total.Add(c)
+	}
+	if len(names) > 1 {
+		row(total, "total")
+	}
+
+	if len(top) > 0 {
+		fmt.Fprintln(out)
+		for _, l := range top {
+			fmt.Fprintf(out, " %7d %s:%d %s\n", l.words, displayName(l.name), l.line, strings.TrimSpace(l.text))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files could not be read", failed, len(names))
+	}
+	return nil
+}
+
+// Names standard input in per-line reports
+func displayName(name string) string {
+	if name == "" {
+		return "-"
+	}
+	return name
+}
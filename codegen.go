@@ -0,0 +1,190 @@
+package concordance
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+)
+
+type GoOutput int
+
+const (
+	// A sorted []string of the words and a parallel []int of their counts
+	GoSlice GoOutput = iota
+	// A map[string]int from each word to its count
+	GoMap
+	// A function backed by a minimal perfect hash returning a word's count
+	GoPerfectHash
+)
+
+// Give up on a perfect hash bucket after trying this many seeds
+const maxHashSeeds = 1 << 20
+
+// Writes a Go source file to w declaring the words and their counts. pkg is
+// the package clause of the file and name is the identifier of the generated
+// variable or function; GoSlice also declares nameCounts. The output is gofmt
+// formatted and marked as generated code
+func GenerateGo(w io.Writer, pkg, name string, words []WordTuple, output GoOutput) error {
+	sorted := append([]WordTuple(nil), words...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Word < sorted[j].Word
+	})
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by concordance; DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	switch output {
+	case GoSlice:
+		fmt.Fprintf(&b, "var %s = []string{\n", name)
+		for _, t := range sorted {
+			fmt.Fprintf(&b, "%q,\n", t.Word)
+		}
+		fmt.Fprintf(&b, "}\n\nvar %sCounts = []int{\n", name)
+		for _, t := range sorted {
+			fmt.Fprintf(&b, "%d,\n", t.Count)
+		}
+		b.WriteString("}\n")
+	case GoMap:
+		fmt.Fprintf(&b, "var %s = map[string]int{\n", name)
+		for _, t := range sorted {
+			fmt.Fprintf(&b, "%q: %d,\n", t.Word, t.Count)
+		}
+		b.WriteString("}\n")
+	case GoPerfectHash:
+		if err := writePerfectHash(&b, name, sorted); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("concordance: unknown Go output %d", output)
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// Seeded FNV-1a. The generated code contains an identical copy
+func seededHash(seed uint32, s string) uint32 {
+	h := uint32(2166136261) ^ seed
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	h ^= h >> 15
+	h *= 0x2c1b3c6d
+	h ^= h >> 12
+	return h
+}
+
+// Builds a hash-and-displace perfect hash over the words: each word falls into
+// a bucket by its unseeded hash, and each bucket gets the first seed that
+// places all of its words in unused table slots
+func writePerfectHash(b *bytes.Buffer, name string, words []WordTuple) error {
+	n := len(words)
+	if n == 0 {
+		fmt.Fprintf(b, "func %s(word string) (int, bool) {\nreturn 0, false\n}\n", name)
+		return nil
+	}
+	nb := n/2 + 1
+	buckets := make([][]int, nb)
+	for i, t := range words {
+		k := seededHash(0, t.Word) % uint32(nb)
+		buckets[k] = append(buckets[k], i)
+	}
+	order := make([]int, nb)
+	for i := range order {
+		order[i] = i
+	}
+	// Place the biggest buckets first while the table is still empty
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(buckets[order[i]]) > len(buckets[order[j]])
+	})
+
+	seeds := make([]uint32, nb)
+	slots := make([]int, n)
+	for i := range slots {
+		slots[i] = -1
+	}
+	for _, k := range order {
+		if len(buckets[k]) == 0 {
+			continue
+		}
+		placed := false
+		for seed := uint32(1); seed < maxHashSeeds && !placed; seed++ {
+			taken := make([]uint32, 0, len(buckets[k]))
+			placed = true
+			for _, i := range buckets[k] {
+				s := seededHash(seed, words[i].Word) % uint32(n)
+				if slots[s] >= 0 || containsUint32(taken, s) {
+					placed = false
+					break
+				}
+				taken = append(taken, s)
+			}
+			if placed {
+				seeds[k] = seed
+				for j, i := range buckets[k] {
+					slots[taken[j]] = i
+				}
+			}
+		}
+		if !placed {
+			return errors.New("concordance: could not build a perfect hash for the words")
+		}
+	}
+
+	fmt.Fprintf(b, "var %sSeeds = [...]uint32{", name)
+	for i, s := range seeds {
+		if i%16 == 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(b, "%d, ", s)
+	}
+	fmt.Fprintf(b, "\n}\n\nvar %sWords = [...]string{\n", name)
+	for _, i := range slots {
+		fmt.Fprintf(b, "%q,\n", words[i].Word)
+	}
+	fmt.Fprintf(b, "}\n\nvar %sCounts = [...]int{\n", name)
+	for _, i := range slots {
+		fmt.Fprintf(b, "%d,\n", words[i].Count)
+	}
+	b.WriteString("}\n\n")
+
+	fmt.Fprintf(b, `// %[1]s returns the count of word and whether it is in the list
+func %[1]s(word string) (int, bool) {
+	seed := %[1]sSeeds[%[1]sHash(0, word)%%uint32(len(%[1]sSeeds))]
+	i := %[1]sHash(seed, word) %% uint32(len(%[1]sWords))
+	if %[1]sWords[i] != word {
+		return 0, false
+	}
+	return %[1]sCounts[i], true
+}
+
+func %[1]sHash(seed uint32, s string) uint32 {
+	h := uint32(2166136261) ^ seed
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	h ^= h >> 15
+	h *= 0x2c1b3c6d
+	h ^= h >> 12
+	return h
+}
+`, name)
+	return nil
+}
+
+func containsUint32(s []uint32, v uint32) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package concordance
+
+import (
+	"bufio"
+	"math"
+	"sort"
+)
+
+type Collocate struct {
+	Word  string
+	Count int
+	// logDice association score, at most 14 and higher for stronger collocates
+	LogDice float64
+}
+
+// Returns the words occurring within span tokens of word anywhere in the
+// corpus, strongest association first. Collocates seen fewer than minCount
+// times are left out
+func (c *Corpus) Collocates(word string, span, minCount int) []Collocate {
+	return c.CollocatesWithCounts(word, span, minCount, c.Concordance(0).Counts)
+}
+
+// Like Collocates but scores against counts, the word counts of the whole
+// corpus, saving a pass over the corpus when they are already known
+func (c *Corpus) CollocatesWithCounts(word string, span, minCount int, counts map[string]int) []Collocate {
+	target := c.normalize(word)
+	co := c.cooccurrences(map[string]bool{target: true}, span)
+	return rankCollocates(co[target], counts[target], counts, minCount)
+}
+
+// Counts, for each of the head words, how often every other word occurs within
+// span tokens of it
+func (c *Corpus) cooccurrences(heads map[string]bool, span int) map[string]map[string]int {
+	co := make(map[string]map[string]int, len(heads))
+	for h := range heads {
+		co[h] = make(map[string]int)
+	}
+	for _, d := range c.Documents {
+		scanner := c.scanner(d)
+		scanner.Split(bufio.ScanWords)
+		words := make([]string, 0, 1024)
+		for scanner.Scan() {
+			if w := c.normalize(scanner.Text()); w != "" {
+				words = append(words, w)
+			}
+		}
+		for i, w := range words {
+			if !heads[w] {
+				continue
+			}
+			for j := i - span; j <= i+span; j++ {
+				if j >= 0 && j < len(words) && j != i {
+					co[w][words[j]]++
+				}
+			}
+		}
+	}
+	return co
+}
+
+// Scores co-occurrence counts with logDice and sorts them, best first
+func rankCollocates(co map[string]int, headCount int, counts map[string]int, minCount int) []Collocate {
+	collocates := make([]Collocate, 0, len(co))
+	for w, n := range co {
+		if n < minCount {
+			continue
+		}
+		dice := 2 * float64(n) / float64(headCount+counts[w])
+		collocates = append(collocates, Collocate{
+			Word:    w,
+			Count:   n,
+			LogDice: 14 + math.Log2(dice),
+		})
+	}
+	sort.Slice(collocates, func(i, j int) bool {
+		if collocates[i].LogDice != collocates[j].LogDice {
+			return collocates[i].LogDice > collocates[j].LogDice
+		}
+		return collocates[i].Word < collocates[j].Word
+	})
+	return collocates
+}
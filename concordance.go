@@ -63,13 +63,7 @@ func WordCount(scanner *bufio.Scanner, caseSensitive bool) (map[string]int, int)
 	m := make(map[string]int, 4096)
 	total := 0
 	for scanner.Scan() {
-		var word string
-		if caseSensitive {
-			word = ScrubWord(scanner.Text())
-		} else {
-			word = ScrubWord(strings.ToLower(scanner.Text()))
-		}
-
+		word := normalizeToken(scanner.Text(), caseSensitive)
 		m[word]++
 		total++
 	}
@@ -79,6 +73,14 @@ func WordCount(scanner *bufio.Scanner, caseSensitive bool) (map[string]int, int)
 	return m, total
 }
 
+// Applies the case folding and scrubbing used by WordCount to a raw token
+func normalizeToken(token string, caseSensitive bool) string {
+	if !caseSensitive {
+		token = strings.ToLower(token)
+	}
+	return ScrubWord(token)
+}
+
 // Takes a word token and strips non alphabetic characters from the beginning
 // and end of the word. Any nonalphabetic characters in the middle of the word
 // are ignored
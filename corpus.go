@@ -0,0 +1,110 @@
+package concordance
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+type Document struct {
+	Name     string
+	Text     string
+	Metadata map[string]string
+}
+
+// Returns a new scanner over the text of the document
+func (d *Document) Scanner() *bufio.Scanner {
+	return bufio.NewScanner(strings.NewReader(d.Text))
+}
+
+type Corpus struct {
+	Documents     []*Document
+	CaseSensitive bool
+	// When set, tokens are run through these in order instead of scrubbing
+	// and case folding, as in WordCountFiltered. Used for every count, KWIC
+	// search and statistic generated from the corpus
+	Filters []Filter
+	// Applied to the text of every document before it is tokenized
+	Regions []RegionRule
+}
+
+// Creates an empty corpus. caseSensitive is applied to every count, KWIC
+// search and statistic generated from the corpus
+func NewCorpus(caseSensitive bool) *Corpus {
+	return &Corpus{CaseSensitive: caseSensitive}
+}
+
+// Reads all of r and appends it to the corpus as a new document
+func (c *Corpus) Add(name string, r io.Reader) (*Document, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d := &Document{Name: name, Text: string(b), Metadata: make(map[string]string)}
+	c.Documents = append(c.Documents, d)
+	return d, nil
+}
+
+// Returns a scanner over the document's text with the corpus's region rules
+// applied
+func (c *Corpus) scanner(d *Document) *bufio.Scanner {
+	if len(c.Regions) == 0 {
+		return d.Scanner()
+	}
+	text, _ := ApplyRegions(d.Text, c.Regions)
+	return bufio.NewScanner(strings.NewReader(text))
+}
+
+// Returns the form of token that the corpus counts, using its filters if it
+// has any
+func (c *Corpus) normalize(token string) string {
+	if len(c.Filters) == 0 {
+		return normalizeToken(token, c.CaseSensitive)
+	}
+	for _, f := range c.Filters {
+		token = f(token)
+	}
+	return token
+}
+
+// Returns the position in the document's original text of every token the
+// scanner for it produces, or nil if the corpus has no region rules and the
+// positions are the same
+func (c *Corpus) positions(d *Document) []int {
+	if len(c.Regions) == 0 {
+		return nil
+	}
+	return maskedPositions(d.Text, regionMask(d.Text, c.Regions))
+}
+
+// Returns what the corpus's region rules exclude from each document, in the
+// order of Documents
+func (c *Corpus) RegionStats() []RegionStats {
+	stats := make([]RegionStats, len(c.Documents))
+	if len(c.Regions) == 0 {
+		return stats
+	}
+	for i, d := range c.Documents {
+		_, stats[i] = ApplyRegions(d.Text, c.Regions)
+	}
+	return stats
+}
+
+// Generates a single Concordance over every document in the corpus
+// topWords :: Specifies the maximum length of the MostUsed array. A value <= 0
+// will return them all
+func (c *Corpus) Concordance(topWords int) *Concordance {
+	con := &Concordance{Counts: make(map[string]int, 4096)}
+	for _, d := range c.Documents {
+		counts, total := WordCountFiltered(c.scanner(d), c.normalize)
+		for k, v := range counts {
+			con.Counts[k] += v
+		}
+		con.Total += total
+	}
+	con.Unique = len(con.Counts)
+	con.process()
+	con.TruncateTopWords(topWords)
+
+	return con
+}
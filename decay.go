@@ -0,0 +1,165 @@
+package concordance
+
+import (
+	"bufio"
+	"container/heap"
+	"math"
+	"sort"
+	"time"
+)
+
+// Scores are rescaled once the growth factor passes e to this power, well
+// before float64 overflows near e^709
+const maxDecayExponent = 300
+
+// Word scores that decay exponentially with event time, for streams where
+// cumulative counts would never forget. A word seen once has a score of 1 that
+// halves every half-life.
+//
+// Rather than decaying every score as time passes, each event is added with a
+// weight of e^(λ(t-t0)), which grows with its time t. Every stored score then
+// shares the same hidden factor e^(λ(t-t0)), so updates stay O(1) and the
+// factor is divided out when scores are read. When the weights grow too large
+// all scores are rescaled to a later t0, which only happens once every few
+// hundred half-lives. Create one with NewDecayedCounts
+type DecayedCounts struct {
+	CaseSensitive bool
+
+	halfLife time.Duration
+	lambda   float64
+	origin   time.Time
+	scores   map[string]float64
+}
+
+type DecayedScore struct {
+	Word  string
+	Score float64
+}
+
+// Creates empty decayed counts. Like time.NewTicker, it panics if halfLife is
+// not positive, since no score could be computed from it
+func NewDecayedCounts(halfLife time.Duration, caseSensitive bool) *DecayedCounts {
+	if halfLife <= 0 {
+		panic("concordance: non-positive half-life for NewDecayedCounts")
+	}
+	return &DecayedCounts{
+		CaseSensitive: caseSensitive,
+		halfLife:      halfLife,
+		lambda:        math.Ln2 / halfLife.Seconds(),
+		scores:        make(map[string]float64, 4096),
+	}
+}
+
+// Returns the half-life the counts were created with
+func (d *DecayedCounts) HalfLife() time.Duration {
+	return d.halfLife
+}
+
+// The growth factor e^(λ(t-t0)) of an event at time t
+func (d *DecayedCounts) exponent(t time.Time) float64 {
+	return d.lambda * t.Sub(d.origin).Seconds()
+}
+
+// Adds one occurrence of word at event time t. Events may arrive out of order
+func (d *DecayedCounts) Add(word string, t time.Time) {
+	word = normalizeToken(word, d.CaseSensitive)
+	if word == "" {
+		return
+	}
+	if d.origin.IsZero() {
+		d.origin = t
+	}
+	if d.exponent(t) > maxDecayExponent {
+		d.rescale(t)
+	}
+	d.scores[word] += math.Exp(d.exponent(t))
+}
+
+// Adds every word in the scanner at event time t
+func (d *DecayedCounts) AddText(scanner *bufio.Scanner, t time.Time) {
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		d.Add(scanner.Text(), t)
+	}
+}
+
+// Moves t0 to t, shrinking every stored score to match
+func (d *DecayedCounts) rescale(t time.Time) {
+	factor := math.Exp(-d.exponent(t))
+	for w, s := range d.scores {
+		d.scores[w] = s * factor
+	}
+	d.origin = t
+}
+
+// Returns the score of word at time now
+func (d *DecayedCounts) Score(word string, now time.Time) float64 {
+	s, ok := d.scores[normalizeToken(word, d.CaseSensitive)]
+	if !ok {
+		return 0
+	}
+	return s * math.Exp(-d.exponent(now))
+}
+
+// Returns the number of words with a score
+func (d *DecayedCounts) Len() int {
+	return len(d.scores)
+}
+
+// A min-heap of scores, holding the best k seen so far with the weakest on top
+type scoreHeap []DecayedScore
+
+func (h scoreHeap) Len() int { return len(h) }
+func (h scoreHeap) Less(i, j int) bool {
+	if h[i].Score != h[j].Score {
+		return h[i].Score < h[j].Score
+	}
+	return h[i].Word > h[j].Word
+}
+func (h scoreHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *scoreHeap) Push(x interface{}) { *h = append(*h, x.(DecayedScore)) }
+func (h *scoreHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// Returns the k highest scoring words at time now, highest first. Decay scales
+// every score alike, so the ranking does not depend on now, only the scores do.
+// Takes O(n log k) time for n words
+func (d *DecayedCounts) Top(k int, now time.Time) []DecayedScore {
+	if k <= 0 {
+		return make([]DecayedScore, 0)
+	}
+	h := make(scoreHeap, 0, k+1)
+	for w, s := range d.scores {
+		e := DecayedScore{Word: w, Score: s}
+		if len(h) < k {
+			heap.Push(&h, e)
+		} else if (scoreHeap{h[0], e}).Less(0, 1) {
+			h[0] = e
+			heap.Fix(&h, 0)
+		}
+	}
+	factor := math.Exp(-d.exponent(now))
+	for i := range h {
+		h[i].Score *= factor
+	}
+	sort.Sort(sort.Reverse(h))
+	return h
+}
+
+// Forgets words whose score at time now has fallen below min, to keep memory
+// bounded on long streams. Returns the number of words removed
+func (d *DecayedCounts) Prune(min float64, now time.Time) int {
+	threshold := min * math.Exp(d.exponent(now))
+	removed := 0
+	for w, s := range d.scores {
+		if s < threshold {
+			delete(d.scores, w)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,250 @@
+package concordance
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type DictionaryEntry struct {
+	Headword   string
+	Frequency  int
+	Rank       int
+	Dispersion float64
+	Collocates []Collocate
+	Examples   []string
+}
+
+// Example sentences only count words from this many of the corpus's most used
+// words as common vocabulary
+const gdexCommonWords = 3000
+
+// Sentences whose length falls in this range of words score best as examples
+const (
+	gdexMinWords = 10
+	gdexMaxWords = 25
+)
+
+// Opening words that point back to earlier context, which makes a sentence a
+// poor stand-alone example
+var gdexPronouns = map[string]bool{
+	"he": true, "she": true, "it": true, "they": true, "this": true,
+	"that": true, "these": true, "those": true, "him": true, "her": true,
+	"them": true, "his": true, "its": true, "their": true, "there": true,
+}
+
+// Drafts frequency dictionary entries for the n best headwords in the corpus.
+// Headwords are ranked by Juilland's usage coefficient, frequency weighted by
+// how evenly the word is spread across documents, so that words common in a
+// single document do not crowd out the general vocabulary. Each entry carries
+// up to collocates collocates and the examples best example sentences, scored
+// in the style of GDEX
+func (c *Corpus) DictionaryDraft(n, collocates, examples int) []DictionaryEntry {
+	con := c.Concordance(0)
+	dispersion := c.Dispersion()
+
+	ranked := make([]DictionaryEntry, 0, len(con.Counts))
+	for w, f := range con.Counts {
+		ranked = append(ranked, DictionaryEntry{Headword: w, Frequency: f, Dispersion: dispersion[w]})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		ui := float64(ranked[i].Frequency) * ranked[i].Dispersion
+		uj := float64(ranked[j].Frequency) * ranked[j].Dispersion
+		if ui != uj {
+			return ui > uj
+		}
+		return ranked[i].Headword < ranked[j].Headword
+	})
+	if n > 0 && n < len(ranked) {
+		ranked = ranked[:n]
+	}
+
+	heads := make(map[string]int, len(ranked))
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+		heads[ranked[i].Headword] = i
+	}
+
+	if collocates > 0 {
+		set := make(map[string]bool, len(heads))
+		for h := range heads {
+			set[h] = true
+		}
+		co := c.cooccurrences(set, 4)
+		for i := range ranked {
+			e := &ranked[i]
+			e.Collocates = rankCollocates(co[e.Headword], e.Frequency, con.Counts, 2)
+			if len(e.Collocates) > collocates {
+				e.Collocates = e.Collocates[:collocates]
+			}
+		}
+	}
+
+	if examples > 0 {
+		c.pickExamples(ranked, heads, con, examples)
+	}
+	return ranked
+}
+
+// Picks the best scoring example sentences for every entry in one pass over
+// the corpus
+func (c *Corpus) pickExamples(entries []DictionaryEntry, heads map[string]int, con *Concordance, examples int) {
+	common := make(map[string]bool, gdexCommonWords)
+	for i, t := range con.MostUsed {
+		if i == gdexCommonWords {
+			break
+		}
+		common[t.Word] = true
+	}
+
+	type scored struct {
+		sentence string
+		score    float64
+	}
+	best := make([][]scored, len(entries))
+	for _, d := range c.Documents {
+		scanner := c.scanner(d)
+		scanner.Split(ScanSentences)
+		for scanner.Scan() {
+			sentence := scanner.Text()
+			words := make([]string, 0, 32)
+			for _, f := range strings.Fields(sentence) {
+				if w := c.normalize(f); w != "" {
+					words = append(words, w)
+				}
+			}
+			score := GDEXScore(sentence, words, common)
+			seen := make(map[int]bool)
+			for _, w := range words {
+				i, ok := heads[w]
+				if !ok || seen[i] {
+					continue
+				}
+				seen[i] = true
+				// Keep the list sorted best first and no longer than needed
+				b := append(best[i], scored{sentence, score})
+				sort.SliceStable(b, func(x, y int) bool { return b[x].score > b[y].score })
+				if len(b) > examples {
+					b = b[:examples]
+				}
+				best[i] = b
+			}
+		}
+	}
+	for i := range entries {
+		for _, s := range best[i] {
+			entries[i].Examples = append(entries[i].Examples, s.sentence)
+		}
+	}
+}
+
+// Scores how well a sentence works as a dictionary example, from 0 to 1.
+// words are the sentence's scrubbed words and common is the set of words
+// considered everyday vocabulary. Sentences score well when they are of
+// moderate length, use common words, are complete sentences starting with a
+// capital and ending with final punctuation, and do not open with a pronoun
+func GDEXScore(sentence string, words []string, common map[string]bool) float64 {
+	if len(words) == 0 {
+		return 0
+	}
+
+	length := 1.0
+	if len(words) < gdexMinWords {
+		length = float64(len(words)) / gdexMinWords
+	} else if len(words) > gdexMaxWords {
+		length = math.Max(0, 1-float64(len(words)-gdexMaxWords)/gdexMaxWords)
+	}
+
+	known := 0
+	for _, w := range words {
+		if common[strings.ToLower(w)] {
+			known++
+		}
+	}
+	vocabulary := float64(known) / float64(len(words))
+
+	complete := 0.0
+	first, _ := utf8.DecodeRuneInString(sentence)
+	if unicode.IsUpper(first) {
+		complete += 0.5
+	}
+	if strings.ContainsAny(sentence[len(sentence)-1:], ".!?") {
+		complete += 0.5
+	}
+
+	opening := 1.0
+	if gdexPronouns[strings.ToLower(words[0])] {
+		opening = 0
+	}
+
+	return 0.3*length + 0.3*vocabulary + 0.2*complete + 0.2*opening
+}
+
+// Returns Juilland's D for every word in the corpus, measuring how evenly the
+// word is spread across the documents: 1 is perfectly even and 0 is all in
+// one document. With fewer than two documents every word scores 1
+func (c *Corpus) Dispersion() map[string]float64 {
+	parts := make([]map[string]int, len(c.Documents))
+	sizes := make([]float64, len(c.Documents))
+	all := make(map[string]bool, 4096)
+	for i, d := range c.Documents {
+		var total int
+		parts[i], total = WordCountFiltered(c.scanner(d), c.normalize)
+		sizes[i] = float64(total)
+		for w := range parts[i] {
+			all[w] = true
+		}
+	}
+
+	d := make(map[string]float64, len(all))
+	n := float64(len(parts))
+	for w := range all {
+		if len(parts) < 2 {
+			d[w] = 1
+			continue
+		}
+		// Relative frequency of the word in each document
+		mean := 0.0
+		rel := make([]float64, len(parts))
+		for i, p := range parts {
+			if sizes[i] > 0 {
+				rel[i] = float64(p[w]) / sizes[i]
+			}
+			mean += rel[i]
+		}
+		mean /= n
+		variance := 0.0
+		for _, r := range rel {
+			variance += (r - mean) * (r - mean)
+		}
+		sd := math.Sqrt(variance / n)
+		d[w] = math.Max(0, 1-sd/mean/math.Sqrt(n-1))
+	}
+	return d
+}
+
+// Writes the entries as a plain text dictionary draft
+func WriteDictionary(w io.Writer, entries []DictionaryEntry) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		fmt.Fprintf(bw, "%d. %s\n", e.Rank, e.Headword)
+		fmt.Fprintf(bw, "   frequency %d, dispersion %.2f\n", e.Frequency, e.Dispersion)
+		if len(e.Collocates) > 0 {
+			words := make([]string, len(e.Collocates))
+			for i, col := range e.Collocates {
+				words[i] = col.Word
+			}
+			fmt.Fprintf(bw, "   collocates: %s\n", strings.Join(words, ", "))
+		}
+		for _, ex := range e.Examples {
+			fmt.Fprintf(bw, "   - %s\n", ex)
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
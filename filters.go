@@ -0,0 +1,163 @@
+package concordance
+
+import (
+	"bufio"
+	"strings"
+	"unicode"
+)
+
+// Transforms a word token before it is counted. Any func(string) string,
+// such as strings.ToLower, can be used as a Filter
+type Filter func(string) string
+
+// Counts words like WordCount but runs each token through the filters in
+// order instead of ScrubWord and case folding. Tokens that filter to an empty
+// string are counted in the total but not in the map
+func WordCountFiltered(scanner *bufio.Scanner, filters ...Filter) (map[string]int, int) {
+	scanner.Split(bufio.ScanWords)
+	m := make(map[string]int, 4096)
+	total := 0
+	for scanner.Scan() {
+		word := scanner.Text()
+		for _, f := range filters {
+			word = f(word)
+		}
+		m[word]++
+		total++
+	}
+
+	delete(m, "")
+	return m, total
+}
+
+// Creates a concordance like NewConcordance, counting words with
+// WordCountFiltered so filtered counts get the same MostUsed list, length
+// histogram and exports as any other
+// topWords :: Specifies the maximum length of the MostUsed array. A value <= 0
+// will return them all
+func NewConcordanceFiltered(scanner *bufio.Scanner, topWords int, filters ...Filter) *Concordance {
+	c := &Concordance{}
+	c.Counts, c.Total = WordCountFiltered(scanner, filters...)
+	c.Unique = len(c.Counts)
+	c.process()
+	c.TruncateTopWords(topWords)
+
+	return c
+}
+
+// The Unicode counterpart to ScrubWord. Strips everything that is not a letter
+// or combining mark from the beginning and end of the word, so it keeps words
+// in any script rather than just ASCII
+func ScrubWordUnicode(s string) string {
+	return strings.TrimFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsMark(r)
+	})
+}
+
+// Filters giving meaningful counts for Arabic text: Unicode scrubbing then
+// NormalizeArabic, followed by StripArabicPrefix when stripPrefixes is true
+func ArabicFilters(stripPrefixes bool) []Filter {
+	filters := []Filter{ScrubWordUnicode, NormalizeArabic}
+	if stripPrefixes {
+		filters = append(filters, StripArabicPrefix)
+	}
+	return filters
+}
+
+// Filters giving meaningful counts for Hebrew text: Unicode scrubbing then
+// NormalizeHebrew, followed by StripHebrewPrefix when stripPrefixes is true
+func HebrewFilters(stripPrefixes bool) []Filter {
+	filters := []Filter{ScrubWordUnicode, NormalizeHebrew}
+	if stripPrefixes {
+		filters = append(filters, StripHebrewPrefix)
+	}
+	return filters
+}
+
+// Removes tashkeel (short vowel and other diacritic marks) and tatweel, and
+// unifies the alef, yaa and taa marbuta variants to a single form each
+func NormalizeArabic(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == 'ـ':
+			// Tatweel
+			return -1
+		case inRuneRange(r, 0x0610, 0x061A), inRuneRange(r, 0x064B, 0x065F),
+			r == 0x0670, inRuneRange(r, 0x06D6, 0x06ED):
+			// Tashkeel and Quranic annotation marks
+			return -1
+		case r == 'آ', r == 'أ', r == 'إ', r == 'ٱ':
+			return 'ا'
+		case r == 'ى', r == 'ی':
+			return 'ي'
+		case r == 'ة':
+			return 'ه'
+		}
+		return r
+	}, s)
+}
+
+// Arabic prefixes removed by StripArabicPrefix, longest first
+var arabicPrefixes = []string{"وال", "بال", "كال", "فال", "لل", "ال", "و"}
+
+// Light stemming for Arabic that removes one leading conjunction, preposition
+// or definite article prefix, as long as at least two letters remain. The
+// single letter conjunction و is only removed from words of four or more
+// letters since many short words begin with it
+func StripArabicPrefix(s string) string {
+	n := len([]rune(s))
+	for _, p := range arabicPrefixes {
+		pn := len([]rune(p))
+		if strings.HasPrefix(s, p) && n-pn >= 2 && (pn > 1 || n >= 4) {
+			return s[len(p):]
+		}
+	}
+	return s
+}
+
+// Removes niqqud and cantillation marks and replaces the final letter forms
+// with their regular forms, so a word is spelled the same wherever it occurs
+func NormalizeHebrew(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case inRuneRange(r, 0x0591, 0x05BD), r == 0x05BF, r == 0x05C1,
+			r == 0x05C2, r == 0x05C4, r == 0x05C5, r == 0x05C7:
+			return -1
+		case r == 'ך':
+			return 'כ'
+		case r == 'ם':
+			return 'מ'
+		case r == 'ן':
+			return 'נ'
+		case r == 'ף':
+			return 'פ'
+		case r == 'ץ':
+			return 'צ'
+		}
+		return r
+	}, s)
+}
+
+// Hebrew prefix combinations removed by StripHebrewPrefix
+var hebrewPrefixes = []string{
+	"וכש", "וכשה", "ושה", "שה", "וה", "וב", "ול", "ומ", "וכ", "וש", "כש", "מה", "לכ", "בה",
+	"ה", "ו", "ב", "כ", "ל", "מ", "ש",
+}
+
+// Light stemming for Hebrew that removes one run of the prefix letters ו ה ב
+// כ ל מ ש, as long as at least four letters remain
+func StripHebrewPrefix(s string) string {
+	n := len([]rune(s))
+	best := ""
+	for _, p := range hebrewPrefixes {
+		if strings.HasPrefix(s, p) && n-len([]rune(p)) >= 4 && len(p) > len(best) {
+			best = p
+		}
+	}
+	return s[len(best):]
+}
+
+// Returns true if r is within the range lo..hi inclusive
+func inRuneRange(r, lo, hi rune) bool {
+	return r >= lo && r <= hi
+}
@@ -0,0 +1,361 @@
+package concordance
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Describes an input format and how to turn it into plain text
+type Format struct {
+	Name string
+	// File extensions including the dot, such as ".txt"
+	Extensions []string
+	MIMETypes  []string
+	// Byte prefixes that identify the format
+	Magic [][]byte
+	// Reports whether the start of a file is in this format, for formats that
+	// have no fixed magic bytes. head holds up to sniffLength bytes
+	Sniff func(head []byte) bool
+	// Returns the plain text of r, or for compressed formats the decompressed
+	// stream, whose own format is then detected in turn
+	Decode     func(r io.Reader) (io.Reader, error)
+	Compressed bool
+	// Set for archive formats holding several files. Calls fn with the name and
+	// contents of each file in the archive
+	Unpack func(r io.Reader, fn func(name string, r io.Reader) error) error
+}
+
+// The number of bytes read from the start of an input to detect its format
+const sniffLength = 512
+
+var ErrUnknownFormat = errors.New("concordance: unknown input format")
+
+var (
+	formatsMu sync.RWMutex
+	formats   []Format
+)
+
+// Adds a format to the registry. Formats registered later take precedence over
+// earlier ones, so a package can replace a built in format by registering one
+// that matches the same inputs
+func RegisterFormat(f Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats = append(formats, f)
+}
+
+// Returns the registered formats, most recently registered first
+func Formats() []Format {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	fs := make([]Format, len(formats))
+	for i, f := range formats {
+		fs[len(formats)-1-i] = f
+	}
+	return fs
+}
+
+// Picks the format of an input from, in order of preference, its magic bytes,
+// its MIME type, its file name extension and content sniffing. Input that
+// matches none of them but is valid UTF-8 is treated as plain text
+func DetectFormat(name, mimeType string, head []byte) (Format, bool) {
+	fs := Formats()
+	for _, f := range fs {
+		for _, magic := range f.Magic {
+			if bytes.HasPrefix(head, magic) {
+				return f, true
+			}
+		}
+	}
+	if mimeType != "" {
+		if mt, _, err := mime.ParseMediaType(mimeType); err == nil {
+			for _, f := range fs {
+				for _, m := range f.MIMETypes {
+					if m == mt {
+						return f, true
+					}
+				}
+			}
+		}
+	}
+	if ext := strings.ToLower(path.Ext(name)); ext != "" {
+		for _, f := range fs {
+			for _, e := range f.Extensions {
+				if e == ext {
+					return f, true
+				}
+			}
+		}
+	}
+	for _, f := range fs {
+		if f.Sniff != nil && f.Sniff(head) {
+			return f, true
+		}
+	}
+	if utf8.Valid(trimPartialRune(head)) {
+		for _, f := range fs {
+			if f.Name == "text" {
+				return f, true
+			}
+		}
+	}
+	return Format{}, false
+}
+
+// Drops a rune cut off by the end of the sniffed bytes so it is not mistaken
+// for invalid UTF-8
+func trimPartialRune(b []byte) []byte {
+	for i := 0; i < utf8.UTFMax && i < len(b); i++ {
+		if utf8.RuneStart(b[len(b)-1-i]) {
+			if !utf8.FullRune(b[len(b)-1-i:]) {
+				return b[:len(b)-1-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// Detects the format of r and calls fn with the name, format name and plain
+// text of every file it holds: once for a single file, or once per file for
+// archives. Compressed input is decompressed and detected again, with the
+// compression extension removed from its name
+func ReadFormatted(name, mimeType string, r io.Reader, fn func(name, format string, text io.Reader) error) error {
+	br := bufio.NewReaderSize(r, sniffLength)
+	head, err := br.Peek(sniffLength)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return err
+	}
+	f, ok := DetectFormat(name, mimeType, head)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+	}
+
+	switch {
+	case f.Unpack != nil:
+		return f.Unpack(br, func(inner string, r io.Reader) error {
+			return ReadFormatted(name+"/"+inner, "", r, fn)
+		})
+	case f.Compressed:
+		dr, err := f.Decode(br)
+		if err != nil {
+			return err
+		}
+		return ReadFormatted(strings.TrimSuffix(name, path.Ext(name)), "", dr, fn)
+	}
+	text, err := f.Decode(br)
+	if err != nil {
+		return err
+	}
+	return fn(name, f.Name, text)
+}
+
+// Adds the file at path to the corpus, picking the reader for it from the
+// format registry. Archives add one document per file they contain
+func (c *Corpus) AddFile(p string) ([]*Document, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return c.AddFormatted(filepath.ToSlash(p), "", file)
+}
+
+// Adds the contents of r to the corpus, picking the reader for it from the
+// format registry by name, MIME type and content. Archives add one document
+// per file they contain
+func (c *Corpus) AddFormatted(name, mimeType string, r io.Reader) ([]*Document, error) {
+	docs := make([]*Document, 0, 1)
+	err := ReadFormatted(name, mimeType, r, func(name, format string, text io.Reader) error {
+		d, err := c.Add(name, text)
+		if err == nil {
+			d.Metadata["format"] = format
+			docs = append(docs, d)
+		}
+		return err
+	})
+	return docs, err
+}
+
+func init() {
+	RegisterFormat(Format{
+		Name:       "text",
+		Extensions: []string{".txt", ".text"},
+		MIMETypes:  []string{"text/plain"},
+		Decode:     func(r io.Reader) (io.Reader, error) { return r, nil },
+	})
+	RegisterFormat(Format{
+		Name:       "markdown",
+		Extensions: []string{".md", ".markdown"},
+		MIMETypes:  []string{"text/markdown"},
+		Decode:     decodeWith(MarkdownToText),
+	})
+	RegisterFormat(Format{
+		Name:       "html",
+		Extensions: []string{".html", ".htm", ".xhtml"},
+		MIMETypes:  []string{"text/html", "application/xhtml+xml"},
+		Sniff: func(head []byte) bool {
+			h := bytes.ToLower(head)
+			return bytes.Contains(h, []byte("<!doctype html")) || bytes.Contains(h, []byte("<html"))
+		},
+		Decode: decodeWith(HTMLToText),
+	})
+	RegisterFormat(Format{
+		Name:       "gzip",
+		Extensions: []string{".gz"},
+		MIMETypes:  []string{"application/gzip", "application/x-gzip"},
+		Magic:      [][]byte{{0x1f, 0x8b}},
+		Compressed: true,
+		Decode:     func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	})
+	RegisterFormat(Format{
+		Name:       "bzip2",
+		Extensions: []string{".bz2"},
+		MIMETypes:  []string{"application/x-bzip2"},
+		Magic:      bzip2Magic(),
+		Compressed: true,
+		Decode:     func(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil },
+	})
+	RegisterFormat(Format{
+		Name:       "tar",
+		Extensions: []string{".tar"},
+		MIMETypes:  []string{"application/x-tar"},
+		Sniff: func(head []byte) bool {
+			return len(head) >= 262 && bytes.Equal(head[257:262], []byte("ustar"))
+		},
+		Unpack: unpackTar,
+	})
+	RegisterFormat(Format{
+		Name:       "zip",
+		Extensions: []string{".zip"},
+		MIMETypes:  []string{"application/zip"},
+		Magic:      [][]byte{[]byte("PK\x03\x04")},
+		Unpack:     unpackZip,
+	})
+}
+
+// "BZh" followed by the block size digit
+func bzip2Magic() [][]byte {
+	magic := make([][]byte, 0, 9)
+	for d := byte('1'); d <= '9'; d++ {
+		magic = append(magic, []byte{'B', 'Z', 'h', d})
+	}
+	return magic
+}
+
+// Adapts a string conversion into a Decode function
+func decodeWith(convert func(string) string) func(io.Reader) (io.Reader, error) {
+	return func(r io.Reader) (io.Reader, error) {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(convert(string(b))), nil
+	}
+}
+
+func unpackTar(r io.Reader, fn func(string, io.Reader) error) error {
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if h.Typeflag == tar.TypeReg {
+			if err := fn(h.Name, tr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Zip needs random access, so the whole archive is read into memory
+func unpackZip(r io.Reader, fn func(string, io.Reader) error) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = fn(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	htmlComment   = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlScript    = regexp.MustCompile(`(?is)<script\b.*?</script\s*>`)
+	htmlStyle     = regexp.MustCompile(`(?is)<style\b.*?</style\s*>`)
+	htmlInlineTag = regexp.MustCompile(`(?i)</?(?:a|abbr|b|code|em|i|small|span|strong|sub|sup|u)\b[^>]*>`)
+	htmlTag       = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Strips the markup from an HTML document, leaving its text. Scripts, styles
+// and comments are removed entirely and entities are decoded
+func HTMLToText(s string) string {
+	s = htmlComment.ReplaceAllString(s, " ")
+	s = htmlScript.ReplaceAllString(s, " ")
+	s = htmlStyle.ReplaceAllString(s, " ")
+	// Inline tags can sit inside a word, other tags separate text
+	s = htmlInlineTag.ReplaceAllString(s, "")
+	s = htmlTag.ReplaceAllString(s, "\n")
+	return html.UnescapeString(s)
+}
+
+var markdownRules = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile("(?m)^\\s*(?:```|~~~).*$"), ""},
+	{regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s*`), ""},
+	{regexp.MustCompile(`(?m)^\s{0,3}>\s?`), ""},
+	{regexp.MustCompile(`(?m)^\s*(?:[-*+]|\d+[.)])\s+`), ""},
+	{regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`), "$1"},
+	{regexp.MustCompile(`<[^>]*>`), " "},
+	// Emphasis and code delimiters only at the edges of words, so snake_case
+	// and other identifiers keep their underscores
+	{regexp.MustCompile("(^|[^\\p{L}\\p{N}*_`~])[*_`~]+([^\\s*_`~])"), "$1$2"},
+	{regexp.MustCompile("([^\\s*_`~])[*_`~]+($|[^\\p{L}\\p{N}*_`~])"), "$1$2"},
+}
+
+// Strips Markdown syntax, leaving its text. Link and image text is kept while
+// their targets are dropped
+func MarkdownToText(s string) string {
+	for _, rule := range markdownRules {
+		s = rule.re.ReplaceAllString(s, rule.repl)
+	}
+	return s
+}
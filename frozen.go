@@ -0,0 +1,294 @@
+package concordance
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Identifies a frozen snapshot and its format version
+const frozenMagic = "CONCFRZ1"
+
+var ErrBadSnapshot = errors.New("concordance: not a frozen snapshot")
+
+// An immutable, compact form of a Concordance for fast lookups once counting
+// is done. Words are kept in one sorted string with offsets into it rather
+// than as map keys, lookups are a binary search and ranks are precomputed
+type Frozen struct {
+	Total           int
+	LengthHistogram []int
+
+	words   string
+	offsets []uint32
+	counts  []uint64
+	byRank  []uint32
+	ranks   []uint32
+}
+
+// Converts the concordance into a Frozen one. The concordance is not changed
+// and can be discarded afterwards
+func (c *Concordance) Freeze() *Frozen {
+	sorted := make([]string, 0, len(c.Counts))
+	size := 0
+	for k := range c.Counts {
+		sorted = append(sorted, k)
+		size += len(k)
+	}
+	sort.Strings(sorted)
+
+	f := &Frozen{
+		Total:           c.Total,
+		LengthHistogram: append([]int(nil), c.LengthHistogram...),
+		offsets:         make([]uint32, 0, len(sorted)+1),
+		counts:          make([]uint64, 0, len(sorted)),
+	}
+	var b strings.Builder
+	b.Grow(size)
+	for _, k := range sorted {
+		f.offsets = append(f.offsets, uint32(b.Len()))
+		f.counts = append(f.counts, uint64(c.Counts[k]))
+		b.WriteString(k)
+	}
+	f.offsets = append(f.offsets, uint32(b.Len()))
+	f.words = b.String()
+	f.rank()
+	return f
+}
+
+// Orders the words by count, most used first, with ties in alphabetical order
+func (f *Frozen) rank() {
+	n := f.Len()
+	f.byRank = make([]uint32, n)
+	for i := range f.byRank {
+		f.byRank[i] = uint32(i)
+	}
+	sort.SliceStable(f.byRank, func(i, j int) bool {
+		return f.counts[f.byRank[i]] > f.counts[f.byRank[j]]
+	})
+	f.ranks = make([]uint32, n)
+	for r, i := range f.byRank {
+		f.ranks[i] = uint32(r)
+	}
+}
+
+// Returns the number of unique words
+func (f *Frozen) Len() int {
+	return len(f.counts)
+}
+
+// Returns the ith word in alphabetical order
+func (f *Frozen) Word(i int) string {
+	return f.words[f.offsets[i]:f.offsets[i+1]]
+}
+
+// Returns the alphabetical index of word and whether it was found
+func (f *Frozen) Index(word string) (int, bool) {
+	i := sort.Search(f.Len(), func(i int) bool {
+		return f.Word(i) >= word
+	})
+	return i, i < f.Len() && f.Word(i) == word
+}
+
+// Returns the number of occurrences of word, 0 if it never occurs
+func (f *Frozen) Count(word string) int {
+	if i, ok := f.Index(word); ok {
+		return int(f.counts[i])
+	}
+	return 0
+}
+
+// Returns the frequency rank of word, where 1 is the most used word, and
+// whether the word was found
+func (f *Frozen) Rank(word string) (int, bool) {
+	if i, ok := f.Index(word); ok {
+		return int(f.ranks[i]) + 1, true
+	}
+	return 0, false
+}
+
+// Returns the word with the given frequency rank, where 1 is the most used.
+// Panics if rank is not between 1 and Len
+func (f *Frozen) AtRank(rank int) WordTuple {
+	i := f.byRank[rank-1]
+	return WordTuple{Word: f.Word(int(i)), Count: int(f.counts[i])}
+}
+
+// Returns up to n of the most used words. A value <= 0 returns them all
+func (f *Frozen) TopWords(n int) ByCount {
+	if n <= 0 || n > f.Len() {
+		n = f.Len()
+	}
+	top := make(ByCount, 0, n)
+	for r := 1; r <= n; r++ {
+		top = append(top, f.AtRank(r))
+	}
+	return top
+}
+
+// Expands the frozen form back into a regular Concordance
+// topWords :: Specifies the maximum length of the MostUsed array. A value <= 0
+// will return them all
+func (f *Frozen) Thaw(topWords int) *Concordance {
+	c := &Concordance{
+		Counts:          make(map[string]int, f.Len()),
+		Total:           f.Total,
+		Unique:          f.Len(),
+		LengthHistogram: append([]int(nil), f.LengthHistogram...),
+	}
+	for i := 0; i < f.Len(); i++ {
+		c.Counts[f.Word(i)] = int(f.counts[i])
+	}
+	c.MostUsed = f.TopWords(topWords)
+	return c
+}
+
+// Writes the frozen concordance as a snapshot that LoadFrozen can read back
+// without re-sorting or re-ranking anything
+func (f *Frozen) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	header := []uint64{
+		uint64(f.Total),
+		uint64(len(f.LengthHistogram)),
+		uint64(f.Len()),
+		uint64(len(f.words)),
+	}
+	hist := make([]uint64, len(f.LengthHistogram))
+	for i, v := range f.LengthHistogram {
+		hist[i] = uint64(v)
+	}
+
+	io.WriteString(cw, frozenMagic)
+	for _, data := range []interface{}{header, hist, f.offsets, f.counts, f.byRank} {
+		binary.Write(cw, binary.LittleEndian, data)
+	}
+	io.WriteString(cw, f.words)
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// Reads a snapshot written by Frozen.WriteTo
+func LoadFrozen(r io.Reader) (*Frozen, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(frozenMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != frozenMagic {
+		return nil, ErrBadSnapshot
+	}
+	header, err := readUint64s(br, 4)
+	if err != nil {
+		return nil, err
+	}
+	histLen, n, wordsLen := header[1], header[2], header[3]
+	// Empty words are never stored, so there are at most as many words as
+	// bytes of them, and offsets into the words are 32 bits
+	if histLen > 1<<16 || n > wordsLen || wordsLen > math.MaxUint32 {
+		return nil, ErrBadSnapshot
+	}
+
+	f := &Frozen{Total: int(header[0])}
+	hist, err := readUint64s(br, histLen)
+	if err != nil {
+		return nil, err
+	}
+	if f.offsets, err = readUint32s(br, n+1); err != nil {
+		return nil, err
+	}
+	if f.counts, err = readUint64s(br, n); err != nil {
+		return nil, err
+	}
+	if f.byRank, err = readUint32s(br, n); err != nil {
+		return nil, err
+	}
+	var words strings.Builder
+	if _, err := io.CopyN(&words, br, int64(wordsLen)); err != nil {
+		return nil, snapshotError(err)
+	}
+	f.words = words.String()
+
+	f.LengthHistogram = make([]int, histLen)
+	for i, v := range hist {
+		f.LengthHistogram[i] = int(v)
+	}
+	f.ranks = make([]uint32, n)
+	for r, i := range f.byRank {
+		if uint64(i) >= n {
+			return nil, ErrBadSnapshot
+		}
+		f.ranks[i] = uint32(r)
+	}
+	for i := uint64(0); i < n; i++ {
+		if f.offsets[i] > f.offsets[i+1] || uint64(f.offsets[i+1]) > wordsLen {
+			return nil, ErrBadSnapshot
+		}
+	}
+	return f, nil
+}
+
+// The most values read at once. Sections grow as their data arrives, so a
+// corrupt header claiming a huge section fails on the short read rather than
+// allocating all of it up front
+const frozenChunk = 1 << 16
+
+// Reads n little endian uint64s
+func readUint64s(r io.Reader, n uint64) ([]uint64, error) {
+	s := make([]uint64, 0, chunkLen(n))
+	for left := n; left > 0; left = n - uint64(len(s)) {
+		chunk := make([]uint64, chunkLen(left))
+		if err := binary.Read(r, binary.LittleEndian, chunk); err != nil {
+			return nil, snapshotError(err)
+		}
+		s = append(s, chunk...)
+	}
+	return s, nil
+}
+
+// Reads n little endian uint32s
+func readUint32s(r io.Reader, n uint64) ([]uint32, error) {
+	s := make([]uint32, 0, chunkLen(n))
+	for left := n; left > 0; left = n - uint64(len(s)) {
+		chunk := make([]uint32, chunkLen(left))
+		if err := binary.Read(r, binary.LittleEndian, chunk); err != nil {
+			return nil, snapshotError(err)
+		}
+		s = append(s, chunk...)
+	}
+	return s, nil
+}
+
+func chunkLen(n uint64) int {
+	if n > frozenChunk {
+		return frozenChunk
+	}
+	return int(n)
+}
+
+// Reports a snapshot that ends early as a bad snapshot
+func snapshotError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrBadSnapshot
+	}
+	return err
+}
+
+// Counts bytes written and keeps the first error so a run of writes can be
+// checked once at the end
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,296 @@
+package concordance
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Place struct {
+	Name       string
+	Alternates []string
+	Lat, Lon   float64
+	Country    string
+}
+
+// A list of places matched against text by name. Names of several words are
+// matched as a whole, and when two names overlap the longer one wins, so
+// "New York City" is not also counted as "York"
+type Gazetteer struct {
+	Places        []Place
+	CaseSensitive bool
+
+	// Place names indexed by their last word
+	byLast map[string][]placeName
+	maxLen int
+}
+
+type placeName struct {
+	place int
+	words []string
+}
+
+// Reads a tab separated gazetteer with one place per line: name, alternate
+// names separated by commas, latitude, longitude and country. Blank lines and
+// lines starting with # are skipped, as is a header before the first place
+// whose latitude is not a number. Where several places share a name, mentions
+// go to the one listed first, so list the best known places first. Place
+// names are usually capitalised, so matching case sensitively avoids counting
+// words like "nice"
+func LoadGazetteer(r io.Reader, caseSensitive bool) (*Gazetteer, error) {
+	g := &Gazetteer{
+		Places:        make([]Place, 0),
+		CaseSensitive: caseSensitive,
+		byLast:        make(map[string][]placeName),
+	}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	// The header, if any, is the first line that is not blank or a comment
+	first := true
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		header := first
+		first = false
+		fields := strings.Split(text, "\t")
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("concordance: gazetteer line %d: want 5 tab separated fields, got %d", line, len(fields))
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(fields[2]), 64)
+		if err != nil && header {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("concordance: gazetteer line %d: bad latitude %q", line, fields[2])
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(fields[3]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("concordance: gazetteer line %d: bad longitude %q", line, fields[3])
+		}
+		p := Place{
+			Name:       strings.TrimSpace(fields[0]),
+			Alternates: make([]string, 0),
+			Lat:        lat,
+			Lon:        lon,
+			Country:    strings.TrimSpace(fields[4]),
+		}
+		for _, a := range strings.Split(fields[1], ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				p.Alternates = append(p.Alternates, a)
+			}
+		}
+		g.add(p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Adds a place and indexes its names
+func (g *Gazetteer) add(p Place) {
+	index := len(g.Places)
+	g.Places = append(g.Places, p)
+	for _, name := range append([]string{p.Name}, p.Alternates...) {
+		words := make([]string, 0)
+		for _, f := range strings.Fields(name) {
+			if w := normalizeToken(f, g.CaseSensitive); w != "" {
+				words = append(words, w)
+			}
+		}
+		if len(words) == 0 {
+			continue
+		}
+		last := words[len(words)-1]
+		// Keep the first place listed for a name
+		taken := false
+		for _, n := range g.byLast[last] {
+			taken = taken || strings.Join(n.words, " ") == strings.Join(words, " ")
+		}
+		if !taken {
+			g.byLast[last] = append(g.byLast[last], placeName{place: index, words: words})
+		}
+		if len(words) > g.maxLen {
+			g.maxLen = len(words)
+		}
+	}
+}
+
+type PlaceMention struct {
+	// Index of the place in the gazetteer
+	Place int
+	// Token positions of the first and last word of the mention, counting
+	// every token the scanner produces
+	Start, End int
+	Text       string
+}
+
+// Finds every mention of a gazetteer place in the scanner's words
+func (g *Gazetteer) FindPlaces(scanner *bufio.Scanner) []PlaceMention {
+	scanner.Split(bufio.ScanWords)
+	mentions := make([]PlaceMention, 0)
+	if g.maxLen == 0 {
+		return mentions
+	}
+
+	type token struct {
+		word, raw string
+		pos       int
+	}
+	window := make([]token, 0, g.maxLen)
+	pos := -1
+	for scanner.Scan() {
+		pos++
+		word := normalizeToken(scanner.Text(), g.CaseSensitive)
+		if word == "" {
+			continue
+		}
+		if len(window) == g.maxLen {
+			copy(window, window[1:])
+			window = window[:g.maxLen-1]
+		}
+		window = append(window, token{word, scanner.Text(), pos})
+
+		// The longest name ending at this word
+		var best *placeName
+		for i, n := range g.byLast[word] {
+			if len(n.words) > len(window) || best != nil && len(n.words) <= len(best.words) {
+				continue
+			}
+			start := len(window) - len(n.words)
+			match := true
+			for j, w := range n.words {
+				if window[start+j].word != w {
+					match = false
+					break
+				}
+			}
+			if match {
+				best = &g.byLast[word][i]
+			}
+		}
+		if best == nil {
+			continue
+		}
+
+		first := window[len(window)-len(best.words):]
+		raw := make([]string, len(first))
+		for i, t := range first {
+			raw[i] = t.raw
+		}
+		m := PlaceMention{Place: best.place, Start: first[0].pos, End: pos, Text: strings.Join(raw, " ")}
+		// An overlapping earlier mention is replaced if this one is longer
+		if last := len(mentions) - 1; last >= 0 && m.Start <= mentions[last].End {
+			if m.End-m.Start > mentions[last].End-mentions[last].Start {
+				mentions[last] = m
+			}
+			continue
+		}
+		mentions = append(mentions, m)
+	}
+	return mentions
+}
+
+type PlaceCount struct {
+	Place Place
+	Count int
+}
+
+type PlaceReport struct {
+	// Places mentioned at least once, most mentioned first
+	Places    []PlaceCount
+	Countries map[string]int
+	Mentions  int
+}
+
+// Counts the mentions per place and per country
+func (g *Gazetteer) Report(mentions []PlaceMention) *PlaceReport {
+	counts := make(map[int]int)
+	r := &PlaceReport{
+		Places:    make([]PlaceCount, 0),
+		Countries: make(map[string]int),
+		Mentions:  len(mentions),
+	}
+	for _, m := range mentions {
+		counts[m.Place]++
+		r.Countries[g.Places[m.Place].Country]++
+	}
+	for i, n := range counts {
+		r.Places = append(r.Places, PlaceCount{Place: g.Places[i], Count: n})
+	}
+	sort.Slice(r.Places, func(i, j int) bool {
+		if r.Places[i].Count != r.Places[j].Count {
+			return r.Places[i].Count > r.Places[j].Count
+		}
+		return r.Places[i].Place.Name < r.Places[j].Place.Name
+	})
+	return r
+}
+
+// Finds the places mentioned in every document of the corpus and counts them.
+// Mention positions count the tokens of the original text, as in Corpus.KWIC
+func (c *Corpus) Places(g *Gazetteer) *PlaceReport {
+	mentions := make([]PlaceMention, 0)
+	for _, d := range c.Documents {
+		found := g.FindPlaces(c.scanner(d))
+		if positions := c.positions(d); positions != nil {
+			for i := range found {
+				found[i].Start = positions[found[i].Start]
+				found[i].End = positions[found[i].End]
+			}
+		}
+		mentions = append(mentions, found...)
+	}
+	return g.Report(mentions)
+}
+
+type geoJSONFeature struct {
+	Type     string `json:"type"`
+	Geometry struct {
+		Type        string     `json:"type"`
+		Coordinates [2]float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties struct {
+		Name    string  `json:"name"`
+		Country string  `json:"country"`
+		Count   int     `json:"count"`
+		Weight  float64 `json:"weight"`
+	} `json:"properties"`
+}
+
+// Writes the mentioned places as a GeoJSON FeatureCollection of points. Each
+// point carries its mention count and a weight from 0 to 1 relative to the
+// most mentioned place, for sizing markers or heat maps
+func WritePlacesGeoJSON(w io.Writer, r *PlaceReport) error {
+	most := 0
+	for _, p := range r.Places {
+		if p.Count > most {
+			most = p.Count
+		}
+	}
+	features := make([]geoJSONFeature, len(r.Places))
+	for i, p := range r.Places {
+		f := &features[i]
+		f.Type = "Feature"
+		f.Geometry.Type = "Point"
+		// GeoJSON puts longitude first
+		f.Geometry.Coordinates = [2]float64{p.Place.Lon, p.Place.Lat}
+		f.Properties.Name = p.Place.Name
+		f.Properties.Country = p.Place.Country
+		f.Properties.Count = p.Count
+		f.Properties.Weight = float64(p.Count) / float64(most)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Type     string           `json:"type"`
+		Features []geoJSONFeature `json:"features"`
+	}{"FeatureCollection", features})
+}
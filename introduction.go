@@ -0,0 +1,119 @@
+package concordance
+
+import (
+	"bufio"
+)
+
+type SegmentIntroduction struct {
+	Segment  int
+	Name     string
+	Tokens   int
+	Types    int
+	NewTypes int
+	// New types per token in the segment
+	Density float64
+	// The words first seen in this segment, in the order they appear
+	Words []string
+}
+
+// Returns the token position of the first occurrence of every word along with
+// the total number of tokens read. Positions count every token the scanner
+// produces, the same way KWIC does
+func FirstOccurrences(scanner *bufio.Scanner, caseSensitive bool) (map[string]int, int) {
+	scanner.Split(bufio.ScanWords)
+	first := make(map[string]int, 4096)
+	pos := 0
+	for scanner.Scan() {
+		word := normalizeToken(scanner.Text(), caseSensitive)
+		if _, ok := first[word]; !ok && word != "" {
+			first[word] = pos
+		}
+		pos++
+	}
+	return first, pos
+}
+
+// Splits a single text into segments of segmentSize tokens and reports how
+// many new words each segment introduces. A segmentSize <= 0 treats the whole
+// text as one segment
+func IntroductionProfile(scanner *bufio.Scanner, caseSensitive bool, segmentSize int) []SegmentIntroduction {
+	scanner.Split(bufio.ScanWords)
+	t := newIntroTracker()
+	for scanner.Scan() {
+		if segmentSize > 0 && t.current != nil && t.current.Tokens == segmentSize {
+			t.finish()
+		}
+		if t.current == nil {
+			t.start("")
+		}
+		t.add(normalizeToken(scanner.Text(), caseSensitive))
+	}
+	if t.current != nil {
+		t.finish()
+	}
+	return t.profile
+}
+
+// Treats each document in the corpus as a segment, in the order they were
+// added, and reports how many new words each one introduces
+func (c *Corpus) IntroductionProfile() []SegmentIntroduction {
+	t := newIntroTracker()
+	for _, d := range c.Documents {
+		scanner := c.scanner(d)
+		scanner.Split(bufio.ScanWords)
+		t.start(d.Name)
+		for scanner.Scan() {
+			t.add(c.normalize(scanner.Text()))
+		}
+		t.finish()
+	}
+	return t.profile
+}
+
+// Tracks words seen across segments while a profile is being built
+type introTracker struct {
+	seen        map[string]bool
+	segmentSeen map[string]bool
+	current     *SegmentIntroduction
+	profile     []SegmentIntroduction
+}
+
+func newIntroTracker() *introTracker {
+	return &introTracker{
+		seen:    make(map[string]bool, 4096),
+		profile: make([]SegmentIntroduction, 0),
+	}
+}
+
+func (t *introTracker) start(name string) {
+	t.current = &SegmentIntroduction{
+		Segment: len(t.profile),
+		Name:    name,
+		Words:   make([]string, 0),
+	}
+	t.segmentSeen = make(map[string]bool)
+}
+
+func (t *introTracker) add(word string) {
+	t.current.Tokens++
+	if word == "" {
+		return
+	}
+	if !t.segmentSeen[word] {
+		t.segmentSeen[word] = true
+		t.current.Types++
+	}
+	if !t.seen[word] {
+		t.seen[word] = true
+		t.current.NewTypes++
+		t.current.Words = append(t.current.Words, word)
+	}
+}
+
+func (t *introTracker) finish() {
+	if t.current.Tokens > 0 {
+		t.current.Density = float64(t.current.NewTypes) / float64(t.current.Tokens)
+	}
+	t.profile = append(t.profile, *t.current)
+	t.current = nil
+}
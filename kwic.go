@@ -0,0 +1,277 @@
+package concordance
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+type KWICHit struct {
+	Doc      int
+	Position int
+	Left     string
+	Keyword  string
+	Right    string
+	// Manual annotations keyed by category, such as "sense" or "function"
+	Labels map[string]string
+}
+
+func (h *KWICHit) String() string {
+	return fmt.Sprintf("%v [%v] %v", h.Left, h.Keyword, h.Right)
+}
+
+// Returns an identifier for the hit made from its document and position, so
+// it stays the same however the hits are sorted, paged or sampled. It is
+// shaped like "d0-p15" so spreadsheets do not read it as a time or number
+func (h *KWICHit) ID() string {
+	return fmt.Sprintf("d%d-p%d", h.Doc, h.Position)
+}
+
+type KWICOrder int
+
+const (
+	// Document order, then token position within the document
+	ByPosition KWICOrder = iota
+	// Alphabetical on the left context, reading outward from the keyword
+	ByLeftContext
+	// Alphabetical on the right context, reading outward from the keyword
+	ByRightContext
+)
+
+// Finds every occurrence of word in the scanner and returns it along with up
+// to span tokens of context on either side. Matching uses the same scrubbing
+// and case rules as WordCount. Position is the index of the token in the
+// input, counting every token the scanner produces
+func KWIC(scanner *bufio.Scanner, word string, caseSensitive bool, span int) []KWICHit {
+	return kwic(scanner, word, func(token string) string {
+		return normalizeToken(token, caseSensitive)
+	}, span)
+}
+
+// Runs KWIC, matching tokens that normalize to the same form as word
+func kwic(scanner *bufio.Scanner, word string, normalize Filter, span int) []KWICHit {
+	scanner.Split(bufio.ScanWords)
+	target := normalize(word)
+	hits := make([]KWICHit, 0)
+	if target == "" {
+		return hits
+	}
+
+	type pendingHit struct {
+		index int
+		right []string
+	}
+	var pending []pendingHit
+	left := make([]string, 0, span+1)
+	pos := 0
+	for scanner.Scan() {
+		tok := scanner.Text()
+
+		// Feed the token to hits that are still collecting right context
+		open := pending[:0]
+		for _, p := range pending {
+			p.right = append(p.right, tok)
+			if len(p.right) == span {
+				hits[p.index].Right = strings.Join(p.right, " ")
+			} else {
+				open = append(open, p)
+			}
+		}
+		pending = open
+
+		if normalize(tok) == target {
+			hits = append(hits, KWICHit{
+				Position: pos,
+				Left:     strings.Join(left, " "),
+				Keyword:  tok,
+			})
+			if span > 0 {
+				pending = append(pending, pendingHit{index: len(hits) - 1})
+			}
+		}
+
+		if span > 0 {
+			if len(left) == span {
+				copy(left, left[1:])
+				left = left[:span-1]
+			}
+			left = append(left, tok)
+		}
+		pos++
+	}
+
+	// Hits near the end of the input get whatever right context exists
+	for _, p := range pending {
+		hits[p.index].Right = strings.Join(p.right, " ")
+	}
+	return hits
+}
+
+// Runs KWIC over every document in the corpus, setting Doc on each hit to the
+// index of the document it came from. Positions count the tokens of the
+// original text, so they do not change with the corpus's region rules
+func (c *Corpus) KWIC(word string, span int) []KWICHit {
+	hits := make([]KWICHit, 0)
+	for i, d := range c.Documents {
+		found := kwic(c.scanner(d), word, c.normalize, span)
+		positions := c.positions(d)
+		for _, h := range found {
+			h.Doc = i
+			if positions != nil {
+				h.Position = positions[h.Position]
+			}
+			hits = append(hits, h)
+		}
+	}
+	return hits
+}
+
+// Sorts the hits in place. Ties are always broken by document and position so
+// the resulting order is stable between runs, which paging relies on
+func SortHits(hits []KWICHit, order KWICOrder) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := &hits[i], &hits[j]
+		switch order {
+		case ByLeftContext:
+			if c := compareContext(reverseFields(a.Left), reverseFields(b.Left)); c != 0 {
+				return c < 0
+			}
+		case ByRightContext:
+			if c := compareContext(strings.Fields(a.Right), strings.Fields(b.Right)); c != 0 {
+				return c < 0
+			}
+		}
+		if a.Doc != b.Doc {
+			return a.Doc < b.Doc
+		}
+		return a.Position < b.Position
+	})
+}
+
+// Compares two context word lists case insensitively, word by word
+func compareContext(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(strings.ToLower(a[i]), strings.ToLower(b[i])); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+// Splits s into words and returns them last to first
+func reverseFields(s string) []string {
+	f := strings.Fields(s)
+	for i, j := 0, len(f)-1; i < j; i, j = i+1, j-1 {
+		f[i], f[j] = f[j], f[i]
+	}
+	return f
+}
+
+// Returns up to limit hits starting at offset. A limit <= 0 returns all the
+// remaining hits. Sort the hits first so that pages do not overlap
+func PageHits(hits []KWICHit, offset, limit int) []KWICHit {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(hits) {
+		return hits[:0]
+	}
+	end := len(hits)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return hits[offset:end]
+}
+
+// Picks n hits at random. The same seed always picks the same hits, and the
+// sample keeps the relative order of the input
+func SampleHits(hits []KWICHit, n int, seed int64) []KWICHit {
+	if n <= 0 || n >= len(hits) {
+		return append([]KWICHit(nil), hits...)
+	}
+	picked := rand.New(rand.NewSource(seed)).Perm(len(hits))[:n]
+	sort.Ints(picked)
+	sample := make([]KWICHit, 0, n)
+	for _, i := range picked {
+		sample = append(sample, hits[i])
+	}
+	return sample
+}
+
+// Keeps every nth hit, starting with the first
+func ThinHits(hits []KWICHit, every int) []KWICHit {
+	if every <= 1 {
+		return append([]KWICHit(nil), hits...)
+	}
+	thinned := make([]KWICHit, 0, len(hits)/every+1)
+	for i := 0; i < len(hits); i += every {
+		thinned = append(thinned, hits[i])
+	}
+	return thinned
+}
+
+// Keeps at most max hits from each document, preferring earlier hits in the
+// slice. A max <= 0 applies no cap
+func CapPerDocument(hits []KWICHit, max int) []KWICHit {
+	if max <= 0 {
+		return append([]KWICHit(nil), hits...)
+	}
+	seen := make(map[int]int)
+	capped := make([]KWICHit, 0, len(hits))
+	for _, h := range hits {
+		if seen[h.Doc] < max {
+			seen[h.Doc]++
+			capped = append(capped, h)
+		}
+	}
+	return capped
+}
+
+// The options of a KWIC search, shared by the command line tool and the HTTP
+// service
+type KWICQuery struct {
+	Word  string
+	Span  int
+	Order KWICOrder
+	// Applied in this order after sorting: at most PerDocument hits from each
+	// document, every Thin-th hit, then a seeded random sample of Sample hits.
+	// Zero values leave the hits alone
+	PerDocument int
+	Thin        int
+	Sample      int
+	Seed        int64
+	// The page of the remaining hits to return
+	Offset, Limit int
+}
+
+// Runs a KWIC search over the corpus and cuts the hits down as the query asks.
+// Returns the page of hits and the number of hits there were before paging
+func (c *Corpus) QueryKWIC(q KWICQuery) ([]KWICHit, int) {
+	hits := c.KWIC(q.Word, q.Span)
+	SortHits(hits, q.Order)
+	if q.PerDocument > 0 {
+		hits = CapPerDocument(hits, q.PerDocument)
+	}
+	if q.Thin > 1 {
+		hits = ThinHits(hits, q.Thin)
+	}
+	if q.Sample > 0 {
+		hits = SampleHits(hits, q.Sample, q.Seed)
+	}
+	return PageHits(hits, q.Offset, q.Limit), len(hits)
+}
+
+// Returns the order named "position", "left" or "right"
+func ParseKWICOrder(name string) (KWICOrder, error) {
+	switch name {
+	case "", "position":
+		return ByPosition, nil
+	case "left":
+		return ByLeftContext, nil
+	case "right":
+		return ByRightContext, nil
+	}
+	return ByPosition, fmt.Errorf("concordance: unknown KWIC order %q", name)
+}
@@ -0,0 +1,84 @@
+package concordance
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// The JSON body of a KWIC search response
+type KWICResponse struct {
+	Word string `json:"word"`
+	// Hits before paging
+	Total  int        `json:"total"`
+	Offset int        `json:"offset"`
+	Hits   []KWICJSON `json:"hits"`
+}
+
+type KWICJSON struct {
+	ID       string `json:"id"`
+	Doc      int    `json:"doc"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+	Left     string `json:"left"`
+	Keyword  string `json:"keyword"`
+	Right    string `json:"right"`
+}
+
+// Returns an HTTP handler serving KWIC searches over the corpus as JSON. The
+// query parameters are word (required), span, sort (position, left or right),
+// per-doc, thin, sample, seed, offset and limit, with the meanings of the
+// KWICQuery fields. Defaults match the command line tool, so span is 5 and seed
+// is 1 unless given. The corpus must not be changed while the handler is in use
+func KWICHandler(c *Corpus) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		q := KWICQuery{Word: params.Get("word"), Span: 5, Seed: 1}
+		if q.Word == "" {
+			http.Error(w, "missing word parameter", http.StatusBadRequest)
+			return
+		}
+		var err error
+		if q.Order, err = ParseKWICOrder(params.Get("sort")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ints := []struct {
+			name string
+			dest *int
+		}{
+			{"span", &q.Span}, {"per-doc", &q.PerDocument}, {"thin", &q.Thin},
+			{"sample", &q.Sample}, {"offset", &q.Offset}, {"limit", &q.Limit},
+		}
+		for _, p := range ints {
+			if v := params.Get(p.name); v != "" {
+				if *p.dest, err = strconv.Atoi(v); err != nil {
+					http.Error(w, "bad "+p.name+" parameter", http.StatusBadRequest)
+					return
+				}
+			}
+		}
+		if v := params.Get("seed"); v != "" {
+			if q.Seed, err = strconv.ParseInt(v, 10, 64); err != nil {
+				http.Error(w, "bad seed parameter", http.StatusBadRequest)
+				return
+			}
+		}
+
+		hits, total := c.QueryKWIC(q)
+		resp := KWICResponse{Word: q.Word, Total: total, Offset: q.Offset, Hits: make([]KWICJSON, len(hits))}
+		for i, h := range hits {
+			resp.Hits[i] = KWICJSON{
+				ID:       h.ID(),
+				Doc:      h.Doc,
+				Name:     c.Documents[h.Doc].Name,
+				Position: h.Position,
+				Left:     h.Left,
+				Keyword:  h.Keyword,
+				Right:    h.Right,
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
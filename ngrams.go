@@ -0,0 +1,43 @@
+package concordance
+
+import (
+	"bufio"
+	"strings"
+)
+
+// Counts every sequence of n consecutive words in the scanner, returning the
+// counts keyed by the words joined with single spaces, and the total number of
+// n-grams counted. Words are scrubbed and cased as in WordCount, and tokens
+// that scrub to nothing are skipped rather than breaking a sequence
+func NGramCount(scanner *bufio.Scanner, n int, caseSensitive bool) (map[string]int, int) {
+	return ngramCount(scanner, n, func(token string) string {
+		return normalizeToken(token, caseSensitive)
+	})
+}
+
+// Counts n-grams as NGramCount does, with each token run through normalize
+func ngramCount(scanner *bufio.Scanner, n int, normalize Filter) (map[string]int, int) {
+	scanner.Split(bufio.ScanWords)
+	m := make(map[string]int, 4096)
+	total := 0
+	if n <= 0 {
+		return m, total
+	}
+	window := make([]string, 0, n)
+	for scanner.Scan() {
+		word := normalize(scanner.Text())
+		if word == "" {
+			continue
+		}
+		if len(window) == n {
+			copy(window, window[1:])
+			window = window[:n-1]
+		}
+		window = append(window, word)
+		if len(window) == n {
+			m[strings.Join(window, " ")]++
+			total++
+		}
+	}
+	return m, total
+}
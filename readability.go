@@ -0,0 +1,134 @@
+package concordance
+
+import (
+	"bufio"
+	"strings"
+	"unicode/utf8"
+)
+
+type TextStats struct {
+	Words     int
+	Sentences int
+	Syllables int
+	// Words of more than six letters
+	LongWords int
+	// Words of three or more syllables
+	Polysyllables int
+	// Words of one syllable
+	Monosyllables int
+}
+
+// Letters counted as vowels when counting syllables
+const syllableVowels = "aeiouyáéíóúàèìòùâêîôûäëïöüÿæœåãõ"
+
+// Splits the input into sentences with ScanSentences and counts the words,
+// sentences and syllables the readability formulas need. lang is the language
+// code used for syllable counting, e.g. "en" or "de"
+func NewTextStats(scanner *bufio.Scanner, lang string) *TextStats {
+	scanner.Split(ScanSentences)
+	lang = baseLanguage(lang)
+	s := &TextStats{}
+	for scanner.Scan() {
+		words := 0
+		for _, f := range strings.Fields(scanner.Text()) {
+			w := ScrubWordUnicode(f)
+			if w == "" {
+				continue
+			}
+			words++
+			syl := CountSyllables(w, lang)
+			s.Syllables += syl
+			if utf8.RuneCountInString(w) > 6 {
+				s.LongWords++
+			}
+			if syl >= 3 {
+				s.Polysyllables++
+			}
+			if syl == 1 {
+				s.Monosyllables++
+			}
+		}
+		if words > 0 {
+			s.Words += words
+			s.Sentences++
+		}
+	}
+	return s
+}
+
+// Estimates the syllables in a word by counting groups of consecutive vowels.
+// For English and French a silent final e is not counted. Every word has at
+// least one syllable
+func CountSyllables(word, lang string) int {
+	w := []rune(strings.ToLower(word))
+	count := 0
+	inVowel := false
+	for _, r := range w {
+		vowel := strings.ContainsRune(syllableVowels, r)
+		if vowel && !inVowel {
+			count++
+		}
+		inVowel = vowel
+	}
+	lang = baseLanguage(lang)
+	if (lang == "en" || lang == "fr") && count > 1 && len(w) > 2 && w[len(w)-1] == 'e' {
+		// "table" and "simple" keep their final syllable
+		if !(lang == "en" && w[len(w)-2] == 'l' && !strings.ContainsRune(syllableVowels, w[len(w)-3])) {
+			count--
+		}
+	}
+	if count == 0 {
+		count = 1
+	}
+	return count
+}
+
+// Returns the language part of a code such as "de-AT", in lower case
+func baseLanguage(lang string) string {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// Computes the readability formulas suited to the language, keyed by name.
+// LIX and RIX are language independent and always included. The others are
+//
+//	en: Flesch
+//	de: Wiener Sachtextformel, Amstad
+//	es: Fernandez Huerta, Szigriszt-Pazos
+//	fr: Kandel-Moles
+//
+// Stats with no words or sentences give an empty map
+func Readability(s *TextStats, lang string) map[string]float64 {
+	scores := make(map[string]float64)
+	if s.Words == 0 || s.Sentences == 0 {
+		return scores
+	}
+	words := float64(s.Words)
+	sentences := float64(s.Sentences)
+	// Average sentence length and average syllables per word
+	asl := words / sentences
+	asw := float64(s.Syllables) / words
+
+	scores["LIX"] = asl + 100*float64(s.LongWords)/words
+	scores["RIX"] = float64(s.LongWords) / sentences
+
+	switch baseLanguage(lang) {
+	case "en":
+		scores["Flesch"] = 206.835 - 1.015*asl - 84.6*asw
+	case "de":
+		ms := 100 * float64(s.Polysyllables) / words
+		iw := 100 * float64(s.LongWords) / words
+		es := 100 * float64(s.Monosyllables) / words
+		scores["Wiener Sachtextformel"] = 0.1935*ms + 0.1672*asl + 0.1297*iw - 0.0327*es - 0.875
+		scores["Amstad"] = 180 - asl - 58.5*asw
+	case "es":
+		scores["Fernandez Huerta"] = 206.84 - 0.60*100*asw - 1.02*100*sentences/words
+		scores["Szigriszt-Pazos"] = 206.835 - 62.3*asw - asl
+	case "fr":
+		scores["Kandel-Moles"] = 207 - 1.015*asl - 73.6*asw
+	}
+	return scores
+}
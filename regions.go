@@ -0,0 +1,193 @@
+package concordance
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type RegionRule struct {
+	// Every line beginning with Prefix is a region. Only used when Start is nil
+	Prefix string
+	// A region begins at a match of Start and runs through the next match of
+	// End after it. A nil End, or no further match, runs to the end of input
+	Start *regexp.Regexp
+	End   *regexp.Regexp
+	// Include rules keep only the text inside their regions while exclude
+	// rules drop the text inside theirs. Excludes win where the two overlap
+	Include bool
+}
+
+type RegionStats struct {
+	Regions int
+	Bytes   int
+	Words   int
+}
+
+// Rules for some commonly ignored regions of plain text
+var (
+	QuotedReplyRule = RegionRule{Prefix: ">"}
+	BracketNoteRule = RegionRule{
+		Start: regexp.MustCompile(`\[`),
+		End:   regexp.MustCompile(`\]`),
+	}
+	FencedCodeRule = RegionRule{
+		Start: regexp.MustCompile("(?m)^```"),
+		End:   regexp.MustCompile("(?m)^```.*$"),
+	}
+)
+
+// Reads all of r, applies the region rules and returns a scanner over the
+// text that is left, along with stats on what was excluded
+func FilterRegions(r io.Reader, rules []RegionRule) (*bufio.Scanner, RegionStats, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, RegionStats{}, err
+	}
+	text, stats := ApplyRegions(string(b), rules)
+	return bufio.NewScanner(strings.NewReader(text)), stats, nil
+}
+
+// Applies the region rules to text and returns what is left. Excluded regions
+// are replaced by a single space, or a newline if they spanned lines, so that
+// the words on either side are not joined together
+func ApplyRegions(text string, rules []RegionRule) (string, RegionStats) {
+	return applyMask(text, regionMask(text, rules))
+}
+
+// Marks the bytes of text that the rules keep
+func regionMask(text string, rules []RegionRule) []bool {
+	keep := make([]bool, len(text))
+	hasInclude := false
+	for _, rule := range rules {
+		if rule.Include {
+			hasInclude = true
+			for _, s := range rule.spans(text) {
+				for i := s[0]; i < s[1]; i++ {
+					keep[i] = true
+				}
+			}
+		}
+	}
+	if !hasInclude {
+		for i := range keep {
+			keep[i] = true
+		}
+	}
+	for _, rule := range rules {
+		if !rule.Include {
+			for _, s := range rule.spans(text) {
+				for i := s[0]; i < s[1]; i++ {
+					keep[i] = false
+				}
+			}
+		}
+	}
+
+	return keep
+}
+
+// Drops the bytes of text not marked to keep, as described for ApplyRegions
+func applyMask(text string, keep []bool) (string, RegionStats) {
+	var out strings.Builder
+	out.Grow(len(text))
+	stats := RegionStats{}
+	i := 0
+	for i < len(text) {
+		j := i
+		for j < len(text) && keep[j] == keep[i] {
+			j++
+		}
+		if keep[i] {
+			out.WriteString(text[i:j])
+		} else {
+			excluded := text[i:j]
+			stats.Regions++
+			stats.Bytes += len(excluded)
+			stats.Words += len(strings.Fields(excluded))
+			if strings.Contains(excluded, "\n") {
+				out.WriteByte('\n')
+			} else {
+				out.WriteByte(' ')
+			}
+		}
+		i = j
+	}
+	return out.String(), stats
+}
+
+// Returns, for every token of the text applyMask leaves, the index of the
+// token of the original text it came from. A token cut in two by an excluded
+// region gives two tokens with the same index
+func maskedPositions(text string, keep []bool) []int {
+	positions := make([]int, 0)
+	token := -1
+	inToken, inKept := false, false
+	for i := 0; i < len(text); {
+		r, width := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsSpace(r) {
+			inToken, inKept = false, false
+			i += width
+			continue
+		}
+		if !inToken {
+			inToken = true
+			token++
+		}
+		if keep[i] && !inKept {
+			positions = append(positions, token)
+		}
+		inKept = keep[i]
+		i += width
+	}
+	return positions
+}
+
+// Returns the byte ranges of text covered by the rule
+func (rule RegionRule) spans(text string) [][2]int {
+	spans := make([][2]int, 0)
+	if rule.Start == nil {
+		if rule.Prefix == "" {
+			return spans
+		}
+		start := 0
+		for start < len(text) {
+			end := strings.IndexByte(text[start:], '\n')
+			if end < 0 {
+				end = len(text)
+			} else {
+				end += start + 1
+			}
+			if strings.HasPrefix(text[start:end], rule.Prefix) {
+				spans = append(spans, [2]int{start, end})
+			}
+			start = end
+		}
+		return spans
+	}
+
+	pos := 0
+	for pos < len(text) {
+		loc := rule.Start.FindStringIndex(text[pos:])
+		if loc == nil {
+			break
+		}
+		start := pos + loc[0]
+		end := len(text)
+		if rule.End != nil {
+			if e := rule.End.FindStringIndex(text[pos+loc[1]:]); e != nil {
+				end = pos + loc[1] + e[1]
+			}
+		}
+		spans = append(spans, [2]int{start, end})
+		if end > pos {
+			pos = end
+		} else {
+			pos++
+		}
+	}
+	return spans
+}
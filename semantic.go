@@ -0,0 +1,363 @@
+package concordance
+
+import (
+	"bufio"
+	"math"
+	"sort"
+)
+
+// Word vectors for one time slice of a corpus. Row i of Vectors belongs to
+// Words[i]
+type WordVectors struct {
+	Words   []string
+	Vectors [][]float64
+	index   map[string]int
+}
+
+// Returns the vector for word and whether the word has one
+func (v *WordVectors) Vector(word string) ([]float64, bool) {
+	i, ok := v.index[word]
+	if !ok {
+		return nil, false
+	}
+	return v.Vectors[i], true
+}
+
+type Neighbor struct {
+	Word       string
+	Similarity float64
+}
+
+// Returns the n words whose vectors are most similar to word's, by cosine
+// similarity
+func (v *WordVectors) Neighbors(word string, n int) []Neighbor {
+	target, ok := v.Vector(word)
+	if !ok {
+		return nil
+	}
+	neighbors := make([]Neighbor, 0, len(v.Words))
+	for i, w := range v.Words {
+		if w != word {
+			neighbors = append(neighbors, Neighbor{Word: w, Similarity: cosine(target, v.Vectors[i])})
+		}
+	}
+	sort.Slice(neighbors, func(i, j int) bool {
+		return neighbors[i].Similarity > neighbors[j].Similarity
+	})
+	if n > 0 && n < len(neighbors) {
+		neighbors = neighbors[:n]
+	}
+	return neighbors
+}
+
+// Builds word vectors for the given vocabulary from one time slice. Each word
+// is first described by its positive pointwise mutual information with every
+// vocabulary word within window tokens of it, and those rows are then reduced
+// to dims dimensions with a truncated SVD. The reduced axes are arbitrary, so
+// vectors from different slices must be aligned with AlignVectors before they
+// are compared
+func (c *Corpus) WordVectors(vocab []string, window, dims int) *WordVectors {
+	index := make(map[string]int, len(vocab))
+	for i, w := range vocab {
+		index[w] = i
+	}
+	n := len(vocab)
+	co := make([][]float64, n)
+	for i := range co {
+		co[i] = make([]float64, n)
+	}
+	for _, d := range c.Documents {
+		scanner := c.scanner(d)
+		scanner.Split(bufio.ScanWords)
+		words := make([]int, 0, 1024)
+		for scanner.Scan() {
+			// Words outside the vocabulary still take up a place in the window
+			i, ok := index[c.normalize(scanner.Text())]
+			if !ok {
+				i = -1
+			}
+			words = append(words, i)
+		}
+		for p, i := range words {
+			if i < 0 {
+				continue
+			}
+			for q := p - window; q <= p+window; q++ {
+				if q >= 0 && q < len(words) && q != p && words[q] >= 0 {
+					co[i][words[q]]++
+				}
+			}
+		}
+	}
+
+	// Positive pointwise mutual information
+	rows := make([]float64, n)
+	cols := make([]float64, n)
+	total := 0.0
+	for i := range co {
+		for j, v := range co[i] {
+			rows[i] += v
+			cols[j] += v
+			total += v
+		}
+	}
+	for i := range co {
+		for j, v := range co[i] {
+			if v > 0 {
+				co[i][j] = math.Max(0, math.Log(v*total/(rows[i]*cols[j])))
+			}
+		}
+	}
+
+	if dims <= 0 || dims > n {
+		dims = n
+	}
+	u, s, _ := svd(co)
+	vectors := make([][]float64, n)
+	for i := range vectors {
+		vectors[i] = make([]float64, dims)
+		for k := 0; k < dims; k++ {
+			vectors[i][k] = u[i][k] * s[k]
+		}
+	}
+	return &WordVectors{Words: append([]string(nil), vocab...), Vectors: vectors, index: index}
+}
+
+// Rotates other onto base with Orthogonal Procrustes: the rotation R
+// minimising |other R - base| over the words both share. Returns the rotated
+// copy of other; neither input is changed
+func AlignVectors(base, other *WordVectors) *WordVectors {
+	dims := 0
+	if len(other.Vectors) > 0 {
+		dims = len(other.Vectors[0])
+	}
+	// M = otherᵀ base over the shared words
+	m := make([][]float64, dims)
+	for i := range m {
+		m[i] = make([]float64, dims)
+	}
+	for i, w := range other.Words {
+		b, ok := base.Vector(w)
+		if !ok || len(b) != dims {
+			continue
+		}
+		o := other.Vectors[i]
+		for x := 0; x < dims; x++ {
+			for y := 0; y < dims; y++ {
+				m[x][y] += o[x] * b[y]
+			}
+		}
+	}
+	// R = U Vᵀ where M = U Σ Vᵀ
+	u, _, v := svd(m)
+	r := make([][]float64, dims)
+	for i := range r {
+		r[i] = make([]float64, dims)
+		for j := range r[i] {
+			for k := 0; k < dims; k++ {
+				r[i][j] += u[i][k] * v[j][k]
+			}
+		}
+	}
+
+	aligned := &WordVectors{
+		Words:   other.Words,
+		Vectors: make([][]float64, len(other.Vectors)),
+		index:   other.index,
+	}
+	for i, o := range other.Vectors {
+		row := make([]float64, dims)
+		for j := 0; j < dims; j++ {
+			for k := 0; k < dims; k++ {
+				row[j] += o[k] * r[k][j]
+			}
+		}
+		aligned.Vectors[i] = row
+	}
+	return aligned
+}
+
+type SemanticShift struct {
+	Word string
+	// Cosine distance between the word's vectors in the first and last slice
+	Distance float64
+	// The word's nearest neighbours in each slice
+	Neighbors [][]Neighbor
+}
+
+// Ranks the words of a diachronic corpus, split into time slices in order, by
+// how much their meaning shifted between the first and last slice. The
+// vocabulary is the topWords most used words of all slices together. Each
+// slice gets its own word vectors, aligned to the previous slice, and the
+// result holds the vocabulary words with their neighbors nearest neighbours in
+// each slice, most shifted first. Words used fewer than minCount times in any
+// slice are left out of the result, since a word missing from a slice has no
+// meaningful vector there. The SVDs take time proportional to the cube of
+// topWords, so keep it to a few thousand words at most
+func SemanticChange(slices []*Corpus, topWords, window, dims, neighbors, minCount int) []SemanticShift {
+	if len(slices) == 0 {
+		return nil
+	}
+	if minCount < 1 {
+		minCount = 1
+	}
+	all := make(map[string]int, 4096)
+	counts := make([]map[string]int, len(slices))
+	for i, s := range slices {
+		counts[i] = s.Concordance(0).Counts
+		for w, n := range counts[i] {
+			all[w] += n
+		}
+	}
+	ranked := make(ByCount, 0, len(all))
+	for w, n := range all {
+		ranked = append(ranked, WordTuple{Word: w, Count: n})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Word < ranked[j].Word
+	})
+	if topWords > 0 && topWords < len(ranked) {
+		ranked = ranked[:topWords]
+	}
+	vocab := make([]string, len(ranked))
+	for i, t := range ranked {
+		vocab[i] = t.Word
+	}
+
+	vectors := make([]*WordVectors, len(slices))
+	for i, s := range slices {
+		vectors[i] = s.WordVectors(vocab, window, dims)
+		if i > 0 {
+			vectors[i] = AlignVectors(vectors[i-1], vectors[i])
+		}
+	}
+
+	first, last := vectors[0], vectors[len(vectors)-1]
+	shifts := make([]SemanticShift, 0, len(vocab))
+	for i, w := range vocab {
+		rare := false
+		for _, c := range counts {
+			rare = rare || c[w] < minCount
+		}
+		if rare {
+			continue
+		}
+		s := SemanticShift{Word: w, Distance: 1 - cosine(first.Vectors[i], last.Vectors[i])}
+		for _, v := range vectors {
+			s.Neighbors = append(s.Neighbors, v.Neighbors(w, neighbors))
+		}
+		shifts = append(shifts, s)
+	}
+	sort.SliceStable(shifts, func(i, j int) bool {
+		return shifts[i].Distance > shifts[j].Distance
+	})
+	return shifts
+}
+
+func cosine(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// Computes the singular value decomposition A = U Σ Vᵀ of an m×n matrix with
+// m >= n by one-sided Jacobi rotations. Singular values are returned largest
+// first, with the columns of U and V in the same order. Columns of U for zero
+// singular values are left as zero vectors
+func svd(a [][]float64) (u [][]float64, s []float64, v [][]float64) {
+	m := len(a)
+	n := 0
+	if m > 0 {
+		n = len(a[0])
+	}
+	// Work on a copy whose columns are rotated until they are orthogonal
+	w := make([][]float64, m)
+	for i := range a {
+		w[i] = append([]float64(nil), a[i]...)
+	}
+	v = make([][]float64, n)
+	for i := range v {
+		v[i] = make([]float64, n)
+		v[i][i] = 1
+	}
+
+	const eps = 1e-12
+	for sweep := 0; sweep < 60; sweep++ {
+		rotated := false
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				var alpha, beta, gamma float64
+				for i := 0; i < m; i++ {
+					alpha += w[i][p] * w[i][p]
+					beta += w[i][q] * w[i][q]
+					gamma += w[i][p] * w[i][q]
+				}
+				if math.Abs(gamma) <= eps*math.Sqrt(alpha*beta) || gamma == 0 {
+					continue
+				}
+				rotated = true
+				zeta := (beta - alpha) / (2 * gamma)
+				t := math.Copysign(1, zeta) / (math.Abs(zeta) + math.Sqrt(1+zeta*zeta))
+				c := 1 / math.Sqrt(1+t*t)
+				sn := c * t
+				for i := 0; i < m; i++ {
+					wp, wq := w[i][p], w[i][q]
+					w[i][p] = c*wp - sn*wq
+					w[i][q] = sn*wp + c*wq
+				}
+				for i := 0; i < n; i++ {
+					vp, vq := v[i][p], v[i][q]
+					v[i][p] = c*vp - sn*vq
+					v[i][q] = sn*vp + c*vq
+				}
+			}
+		}
+		if !rotated {
+			break
+		}
+	}
+
+	// Column norms are the singular values
+	order := make([]int, n)
+	norms := make([]float64, n)
+	for j := 0; j < n; j++ {
+		order[j] = j
+		for i := 0; i < m; i++ {
+			norms[j] += w[i][j] * w[i][j]
+		}
+		norms[j] = math.Sqrt(norms[j])
+	}
+	sort.SliceStable(order, func(x, y int) bool { return norms[order[x]] > norms[order[y]] })
+
+	u = make([][]float64, m)
+	for i := range u {
+		u[i] = make([]float64, n)
+	}
+	s = make([]float64, n)
+	sortedV := make([][]float64, n)
+	for i := range sortedV {
+		sortedV[i] = make([]float64, n)
+	}
+	for k, j := range order {
+		s[k] = norms[j]
+		for i := 0; i < m; i++ {
+			if norms[j] > 0 {
+				u[i][k] = w[i][j] / norms[j]
+			}
+		}
+		for i := 0; i < n; i++ {
+			sortedV[i][k] = v[i][j]
+		}
+	}
+	return u, s, sortedV
+}
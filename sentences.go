@@ -0,0 +1,233 @@
+package concordance
+
+import (
+	"bufio"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type SentenceType int
+
+const (
+	Declarative SentenceType = iota
+	Interrogative
+	Exclamatory
+	Fragment
+)
+
+func (t SentenceType) String() string {
+	switch t {
+	case Declarative:
+		return "declarative"
+	case Interrogative:
+		return "interrogative"
+	case Exclamatory:
+		return "exclamatory"
+	}
+	return "fragment"
+}
+
+// Sentences with fewer words than this are counted as fragments whatever
+// punctuation they end with
+const MinSentenceWords = 2
+
+// Common abbreviations whose trailing period does not end a sentence
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true,
+	"jr": true, "sr": true, "vs": true, "etc": true, "e.g": true, "i.e": true,
+	"fig": true, "cf": true, "approx": true,
+}
+
+// Reports whether a word that is also an abbreviation is used as one, judged
+// by its neighbours: a number after "No.", a name after "St." and "et" before
+// "al.". Otherwise "The answer is no." would not end a sentence
+func contextualAbbreviation(stem, prev string, next rune) bool {
+	switch stem {
+	case "no":
+		return unicode.IsDigit(next)
+	case "st":
+		return unicode.IsUpper(next)
+	case "al":
+		return strings.ToLower(prev) == "et"
+	}
+	return false
+}
+
+// A bufio.SplitFunc that splits input into sentences. A sentence ends at a
+// token ending in '.', '!' or '?' (optionally followed by closing quotes or
+// brackets) unless the token is a known abbreviation or a single initial, and
+// at blank lines. Returned sentences have their whitespace collapsed
+func ScanSentences(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	// Skip leading whitespace
+	start := 0
+	for start < len(data) {
+		r, width := utf8.DecodeRune(data[start:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += width
+	}
+
+	wordStart, prevStart, prevEnd := start, start, start
+	newlines := 0
+	for i := start; i < len(data); {
+		r, width := utf8.DecodeRune(data[i:])
+		if !unicode.IsSpace(r) {
+			newlines = 0
+			i += width
+			continue
+		}
+		if r == '\n' {
+			newlines++
+			if newlines == 2 {
+				return i + width, collapseSpace(data[start:i]), nil
+			}
+		}
+		if wordStart < i {
+			next, ok := nextRune(data[i:], atEOF)
+			if !ok {
+				// Request more data to see the next word
+				return start, nil, nil
+			}
+			if endsSentence(string(data[wordStart:i]), string(data[prevStart:prevEnd]), next) {
+				return i + width, collapseSpace(data[start:i]), nil
+			}
+			prevStart, prevEnd = wordStart, i
+		}
+		i += width
+		wordStart = i
+	}
+
+	if atEOF && len(data) > start {
+		return len(data), collapseSpace(data[start:]), nil
+	}
+	if atEOF {
+		return len(data), nil, nil
+	}
+	// Request more data
+	return start, nil, nil
+}
+
+// Returns the first rune after the leading whitespace of data, or 0 at the end
+// of the input. Returns false if more data is needed to find it
+func nextRune(data []byte, atEOF bool) (rune, bool) {
+	for i := 0; i < len(data); {
+		r, width := utf8.DecodeRune(data[i:])
+		if !unicode.IsSpace(r) {
+			return r, true
+		}
+		i += width
+	}
+	return 0, atEOF
+}
+
+// Returns true if the word token ends with sentence final punctuation. prev is
+// the word before it and next the first rune of the word after it
+func endsSentence(word, prev string, next rune) bool {
+	trimmed := strings.TrimRight(word, "\"')]}»”’")
+	if trimmed == "" {
+		return false
+	}
+	switch trimmed[len(trimmed)-1] {
+	case '!', '?':
+		return true
+	case '.':
+		stem := strings.ToLower(strings.TrimRight(trimmed, "."))
+		stem = strings.TrimLeft(stem, "\"'([{«“‘")
+		if abbreviations[stem] || contextualAbbreviation(stem, prev, next) {
+			return false
+		}
+		// Single initials such as the J. in J. Smith
+		if len(stem) == 1 && alphaChar(stem[0]) {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+func collapseSpace(b []byte) []byte {
+	return []byte(strings.Join(strings.Fields(string(b)), " "))
+}
+
+// Classifies a sentence by its final punctuation. Sentences that are too short
+// or have no final punctuation are fragments
+func ClassifySentence(sentence string) SentenceType {
+	words := 0
+	for _, f := range strings.Fields(sentence) {
+		if ScrubWord(f) != "" {
+			words++
+		}
+	}
+	trimmed := strings.TrimRight(sentence, "\"')]}»”’ ")
+	if words < MinSentenceWords || trimmed == "" {
+		return Fragment
+	}
+	switch trimmed[len(trimmed)-1] {
+	case '?':
+		return Interrogative
+	case '!':
+		return Exclamatory
+	case '.':
+		return Declarative
+	}
+	return Fragment
+}
+
+type SentenceStats struct {
+	Sentences int
+	Types     map[SentenceType]int
+	// Counts of the first word of each sentence
+	Openers map[string]int
+	// Counts of the opening phrase of each sentence, up to the phrase length
+	// passed to NewSentenceStats
+	OpeningPhrases map[string]int
+}
+
+// Splits the input into sentences with ScanSentences and counts sentence
+// types and openers. caseSensitive has the same meaning as for WordCount.
+// phraseLength is the number of words in each opening phrase; sentences
+// shorter than that do not count towards OpeningPhrases
+func NewSentenceStats(scanner *bufio.Scanner, caseSensitive bool, phraseLength int) *SentenceStats {
+	scanner.Split(ScanSentences)
+	s := &SentenceStats{
+		Types:          make(map[SentenceType]int),
+		Openers:        make(map[string]int),
+		OpeningPhrases: make(map[string]int),
+	}
+	limit := phraseLength
+	if limit < 1 {
+		limit = 1
+	}
+	for scanner.Scan() {
+		sentence := scanner.Text()
+		s.Sentences++
+		s.Types[ClassifySentence(sentence)]++
+
+		words := make([]string, 0, limit)
+		for _, f := range strings.Fields(sentence) {
+			if len(words) == limit {
+				break
+			}
+			if w := normalizeToken(f, caseSensitive); w != "" {
+				words = append(words, w)
+			}
+		}
+		if len(words) > 0 {
+			s.Openers[words[0]]++
+		}
+		if phraseLength > 1 && len(words) == phraseLength {
+			s.OpeningPhrases[strings.Join(words, " ")]++
+		}
+	}
+	return s
+}
+
+// Returns the share of sentences of the given type, between 0 and 1
+func (s *SentenceStats) Proportion(t SentenceType) float64 {
+	if s.Sentences == 0 {
+		return 0
+	}
+	return float64(s.Types[t]) / float64(s.Sentences)
+}
@@ -0,0 +1,202 @@
+package concordance
+
+import (
+	"bufio"
+	"sort"
+	"strings"
+)
+
+type Spelling int
+
+const (
+	British Spelling = iota
+	American
+)
+
+type SpellingVariant struct {
+	British  string
+	American string
+	// The rule pairing the two spellings, or "dictionary" for irregular pairs
+	Rule              string
+	BritishCount      int
+	AmericanCount     int
+	BritishPositions  []int
+	AmericanPositions []int
+}
+
+// Irregular British and American spelling pairs that the rules do not cover.
+// Pairs where either spelling is also a different word in the other variety,
+// such as tyre and tire or storey and story, are left out
+var spellingDictionary = map[string]string{
+	"aluminium": "aluminum", "analogue": "analog", "catalogue": "catalog",
+	"cosy": "cozy", "defence": "defense", "doughnut": "donut", "grey": "gray",
+	"jewellery": "jewelry", "manoeuvre": "maneuver", "mould": "mold",
+	"moustache": "mustache", "offence": "offense", "plough": "plow",
+	"pretence": "pretense", "pyjamas": "pajamas", "sceptic": "skeptic",
+	"sceptical": "skeptical", "smoulder": "smolder", "ageing": "aging",
+	"judgement": "judgment", "enrol": "enroll", "fulfil": "fulfill",
+	"skilful": "skillful", "wilful": "willful",
+}
+
+// Suffix rules turning a British spelling into an American candidate. A pair
+// is only reported when both spellings actually occur, since the rules alone
+// would also match words like "four" or "surprise"
+type spellingRule struct {
+	name     string
+	british  []string
+	american []string
+	// If set, the rule only applies to these base words, so it does not pair
+	// different words such as filled and filed
+	bases map[string]bool
+}
+
+// Reports whether the rule applies to a word whose suffix left stem. The
+// suffixes of rules limited to base words all start with the base's final l
+func (r *spellingRule) applies(stem string) bool {
+	return r.bases == nil || r.bases[stem+"l"]
+}
+
+var spellingRules = []spellingRule{
+	{"-our/-or",
+		[]string{"our", "ours", "oured", "ouring", "ourful", "ourite", "ourites", "ourable"},
+		[]string{"or", "ors", "ored", "oring", "orful", "orite", "orites", "orable"}, nil},
+	{"-ise/-ize",
+		[]string{"ise", "ises", "ised", "ising", "isation", "isations", "iser", "isers"},
+		[]string{"ize", "izes", "ized", "izing", "ization", "izations", "izer", "izers"}, nil},
+	{"-yse/-yze",
+		[]string{"yse", "yses", "ysed", "ysing"},
+		[]string{"yze", "yzes", "yzed", "yzing"}, nil},
+	{"-re/-er",
+		[]string{"tre", "tres", "bre", "bres"},
+		[]string{"ter", "ters", "ber", "bers"}, nil},
+	{"doubled consonant",
+		[]string{"lled", "lling", "ller", "llers"},
+		[]string{"led", "ling", "ler", "lers"},
+		wordSet("cancel channel counsel dial duel equal fuel funnel grovel initial jewel label level libel marvel model panel pedal quarrel rival shovel signal snorkel total travel tunnel yodel")},
+}
+
+// Returns the American candidates for a British spelling with the rule that
+// produced each
+func americanCandidates(word string) map[string]string {
+	candidates := make(map[string]string)
+	if a, ok := spellingDictionary[word]; ok {
+		candidates[a] = "dictionary"
+	}
+	// Any inflection of the other spelling counts, so that organise and
+	// organized are reported as a mix of conventions
+	for _, rule := range spellingRules {
+		for _, suffix := range rule.british {
+			stem := strings.TrimSuffix(word, suffix)
+			// Require a stem of a few letters so short words are left alone
+			if stem == word || len(stem) < 2 || !rule.applies(stem) {
+				continue
+			}
+			for _, american := range rule.american {
+				candidates[stem+american] = rule.name
+			}
+		}
+	}
+	return candidates
+}
+
+// Finds words that occur in both their British and American spellings, with
+// the token positions of every use of each spelling. Variants are returned
+// with the most used pair first
+func FindSpellingVariants(scanner *bufio.Scanner, caseSensitive bool) []SpellingVariant {
+	scanner.Split(bufio.ScanWords)
+	positions := make(map[string][]int, 4096)
+	pos := 0
+	for scanner.Scan() {
+		if w := normalizeToken(scanner.Text(), caseSensitive); w != "" {
+			positions[w] = append(positions[w], pos)
+		}
+		pos++
+	}
+
+	variants := make([]SpellingVariant, 0)
+	for w, bp := range positions {
+		for a, rule := range americanCandidates(w) {
+			ap, ok := positions[a]
+			if !ok || a == w {
+				continue
+			}
+			variants = append(variants, SpellingVariant{
+				British:           w,
+				American:          a,
+				Rule:              rule,
+				BritishCount:      len(bp),
+				AmericanCount:     len(ap),
+				BritishPositions:  bp,
+				AmericanPositions: ap,
+			})
+		}
+	}
+	sort.Slice(variants, func(i, j int) bool {
+		ti := variants[i].BritishCount + variants[i].AmericanCount
+		tj := variants[j].BritishCount + variants[j].AmericanCount
+		if ti != tj {
+			return ti > tj
+		}
+		return variants[i].British < variants[j].British
+	})
+	return variants
+}
+
+// Rewrites a word in the other convention by the dictionary or the rule with
+// the longest matching suffix, keeping its inflection
+func respell(word string, to Spelling) string {
+	for b, a := range spellingDictionary {
+		if to == American && word == b {
+			return a
+		}
+		if to == British && word == a {
+			return b
+		}
+	}
+	respelled := word
+	matched := 0
+	for _, rule := range spellingRules {
+		from, into := rule.british, rule.american
+		if to == British {
+			from, into = into, from
+		}
+		for i, suffix := range from {
+			stem := strings.TrimSuffix(word, suffix)
+			if len(suffix) > matched && stem != word && len(stem) >= 2 && rule.applies(stem) {
+				respelled = stem + into[i]
+				matched = len(suffix)
+			}
+		}
+	}
+	return respelled
+}
+
+// Returns a Filter that rewrites words to the given convention. It converts
+// the pairs in variants, typically found with FindSpellingVariants, along
+// with the built in dictionary of irregular pairs. The filter compares whole
+// words, so it belongs after scrubbing and case folding in a filter chain. Use
+// the chain with NewConcordanceFiltered or a corpus's Filters to count the
+// normalized words
+func SpellingNormalizer(variants []SpellingVariant, to Spelling) Filter {
+	m := make(map[string]string, len(variants)+len(spellingDictionary))
+	for b, a := range spellingDictionary {
+		if to == American {
+			m[b] = a
+		} else {
+			m[a] = b
+		}
+	}
+	for _, v := range variants {
+		if to == American {
+			m[v.British] = respell(v.British, American)
+		} else {
+			m[v.American] = respell(v.American, British)
+		}
+	}
+	return func(word string) string {
+		if n, ok := m[word]; ok {
+			return n
+		}
+		return word
+	}
+}
@@ -0,0 +1,182 @@
+package concordance
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type SQLDialect int
+
+const (
+	SQLite SQLDialect = iota
+	PostgreSQL
+)
+
+// The number of rows per INSERT statement when none is given
+const DefaultSQLBatch = 500
+
+// Writes the corpus as a SQL script that creates and fills these tables:
+//
+//	documents(id, name, tokens)
+//	metadata(document_id, key, value)
+//	vocabulary(id, word, count)
+//	document_counts(document_id, word_id, count)
+//	ngrams(document_id, n, ngram, count)
+//
+// ngrams holds every n-gram of 2 up to maxN words; a maxN < 2 leaves it
+// empty. Rows are inserted batchSize at a time inside a single transaction,
+// and a batchSize <= 0 uses DefaultSQLBatch
+func (c *Corpus) WriteSQL(w io.Writer, dialect SQLDialect, maxN, batchSize int) error {
+	if batchSize <= 0 {
+		batchSize = DefaultSQLBatch
+	}
+	countType := "INTEGER"
+	if dialect == PostgreSQL {
+		countType = "BIGINT"
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "BEGIN;")
+	for _, table := range []string{"ngrams", "document_counts", "vocabulary", "metadata", "documents"} {
+		fmt.Fprintf(bw, "DROP TABLE IF EXISTS %s;\n", table)
+	}
+	fmt.Fprintf(bw, `CREATE TABLE documents (
+  id INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  tokens %[1]s NOT NULL
+);
+CREATE TABLE metadata (
+  document_id INTEGER NOT NULL REFERENCES documents(id),
+  key TEXT NOT NULL,
+  value TEXT NOT NULL,
+  PRIMARY KEY (document_id, key)
+);
+CREATE TABLE vocabulary (
+  id INTEGER PRIMARY KEY,
+  word TEXT NOT NULL UNIQUE,
+  count %[1]s NOT NULL
+);
+CREATE TABLE document_counts (
+  document_id INTEGER NOT NULL REFERENCES documents(id),
+  word_id INTEGER NOT NULL REFERENCES vocabulary(id),
+  count %[1]s NOT NULL,
+  PRIMARY KEY (document_id, word_id)
+);
+CREATE TABLE ngrams (
+  document_id INTEGER NOT NULL REFERENCES documents(id),
+  n INTEGER NOT NULL,
+  ngram TEXT NOT NULL,
+  count %[1]s NOT NULL,
+  PRIMARY KEY (document_id, n, ngram)
+);
+`, countType)
+
+	// Count every document first so the vocabulary ids are known before the
+	// per-document rows reference them
+	docCounts := make([]map[string]int, len(c.Documents))
+	vocab := make(map[string]int, 4096)
+	docs := newSQLBatch(bw, "documents", "id, name, tokens", batchSize)
+	meta := newSQLBatch(bw, "metadata", "document_id, key, value", batchSize)
+	for i, d := range c.Documents {
+		counts, total := WordCountFiltered(c.scanner(d), c.normalize)
+		docCounts[i] = counts
+		for k, v := range counts {
+			vocab[k] += v
+		}
+		docs.add(i+1, sqlString(d.Name), total)
+		keys := make([]string, 0, len(d.Metadata))
+		for k := range d.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			meta.add(i+1, sqlString(k), sqlString(d.Metadata[k]))
+		}
+	}
+	docs.flush()
+	meta.flush()
+
+	words := sortedKeys(vocab)
+	ids := make(map[string]int, len(words))
+	vb := newSQLBatch(bw, "vocabulary", "id, word, count", batchSize)
+	for i, word := range words {
+		ids[word] = i + 1
+		vb.add(i+1, sqlString(word), vocab[word])
+	}
+	vb.flush()
+
+	dc := newSQLBatch(bw, "document_counts", "document_id, word_id, count", batchSize)
+	for i, counts := range docCounts {
+		for _, word := range sortedKeys(counts) {
+			dc.add(i+1, ids[word], counts[word])
+		}
+	}
+	dc.flush()
+
+	ng := newSQLBatch(bw, "ngrams", "document_id, n, ngram, count", batchSize)
+	for i, d := range c.Documents {
+		for n := 2; n <= maxN; n++ {
+			grams, _ := ngramCount(c.scanner(d), n, c.normalize)
+			for _, gram := range sortedKeys(grams) {
+				ng.add(i+1, n, sqlString(gram), grams[gram])
+			}
+		}
+	}
+	ng.flush()
+
+	fmt.Fprintln(bw, "COMMIT;")
+	return bw.Flush()
+}
+
+// Collects rows for a table and writes them as multi-row INSERT statements
+type sqlBatch struct {
+	w       *bufio.Writer
+	table   string
+	columns string
+	size    int
+	rows    []string
+}
+
+func newSQLBatch(w *bufio.Writer, table, columns string, size int) *sqlBatch {
+	return &sqlBatch{w: w, table: table, columns: columns, size: size}
+}
+
+// Adds a row. Strings must already be quoted with sqlString
+func (b *sqlBatch) add(values ...interface{}) {
+	fields := make([]string, len(values))
+	for i, v := range values {
+		fields[i] = fmt.Sprint(v)
+	}
+	b.rows = append(b.rows, "("+strings.Join(fields, ", ")+")")
+	if len(b.rows) == b.size {
+		b.flush()
+	}
+}
+
+func (b *sqlBatch) flush() {
+	if len(b.rows) == 0 {
+		return
+	}
+	fmt.Fprintf(b.w, "INSERT INTO %s (%s) VALUES\n  %s;\n", b.table, b.columns, strings.Join(b.rows, ",\n  "))
+	b.rows = b.rows[:0]
+}
+
+// Quotes s as a SQL string literal. NUL bytes, which PostgreSQL rejects in
+// text values, are dropped
+func sqlString(s string) string {
+	s = strings.Replace(s, "\x00", "", -1)
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// Returns the keys of m in sorted order
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
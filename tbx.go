@@ -0,0 +1,209 @@
+package concordance
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type tbxMartif struct {
+	XMLName xml.Name   `xml:"martif"`
+	Type    string     `xml:"type,attr"`
+	Lang    string     `xml:"xml:lang,attr"`
+	Source  string     `xml:"martifHeader>fileDesc>sourceDesc>p"`
+	Entries []tbxEntry `xml:"text>body>termEntry"`
+}
+
+type tbxEntry struct {
+	ID      string `xml:"id,attr"`
+	LangSet struct {
+		Lang string   `xml:"xml:lang,attr"`
+		Tigs []tbxTig `xml:"tig"`
+	} `xml:"langSet"`
+}
+
+type tbxTig struct {
+	Term     string    `xml:"term"`
+	TermNote *tbxNote  `xml:"termNote,omitempty"`
+	Contexts []tbxNote `xml:"descrip,omitempty"`
+	Note     string    `xml:"note,omitempty"`
+}
+
+type tbxNote struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Writes the given terms from the concordance as a TBX termbase. Each term
+// becomes a term entry carrying its frequency, any differently cased variants
+// found in the concordance and the KWIC lines in examples[term] as context
+// sentences. lang is the xml:lang code written for the entries
+func WriteTBX(w io.Writer, c *Concordance, terms []string, examples map[string][]KWICHit, lang string) error {
+	m := tbxMartif{
+		Type:    "TBX",
+		Lang:    lang,
+		Source:  "Generated by concordance",
+		Entries: make([]tbxEntry, 0, len(terms)),
+	}
+	for i, term := range terms {
+		e := tbxEntry{ID: fmt.Sprintf("c%d", i+1)}
+		e.LangSet.Lang = lang
+
+		preferred := tbxTig{
+			Term:     term,
+			TermNote: &tbxNote{Type: "termType", Value: "fullForm"},
+			Note:     fmt.Sprintf("frequency: %d", c.Counts[term]),
+		}
+		for _, h := range examples[term] {
+			preferred.Contexts = append(preferred.Contexts, tbxNote{Type: "context", Value: h.String()})
+		}
+		e.LangSet.Tigs = append(e.LangSet.Tigs, preferred)
+
+		for _, v := range termVariants(c, term) {
+			e.LangSet.Tigs = append(e.LangSet.Tigs, tbxTig{
+				Term:     v,
+				TermNote: &tbxNote{Type: "termType", Value: "variant"},
+				Note:     fmt.Sprintf("frequency: %d", c.Counts[v]),
+			})
+		}
+		m.Entries = append(m.Entries, e)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Returns the other spellings of term in the concordance that differ from it
+// only by case. A case insensitive concordance never has any
+func termVariants(c *Concordance, term string) []string {
+	variants := make([]string, 0)
+	for k := range c.Counts {
+		if k != term && strings.EqualFold(k, term) {
+			variants = append(variants, k)
+		}
+	}
+	sort.Strings(variants)
+	return variants
+}
+
+// Reads a TBX termbase and returns the text of every term in it, in document
+// order. Terms from any TBX dialect are picked up since only the term
+// elements themselves are read
+func ReadTBX(r io.Reader) ([]string, error) {
+	terms := make([]string, 0)
+	dec := xml.NewDecoder(r)
+	inTerm := false
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return terms, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "term" {
+				inTerm = true
+				text.Reset()
+			}
+		case xml.CharData:
+			if inTerm {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "term" && inTerm {
+				inTerm = false
+				if term := strings.TrimSpace(text.String()); term != "" {
+					terms = append(terms, term)
+				}
+			}
+		}
+	}
+}
+
+// Counts occurrences of each term in the scanner. Terms may span several
+// words, in which case they only match the same words in sequence. Words are
+// compared after the same scrubbing and case rules as WordCount
+func CountTerms(scanner *bufio.Scanner, terms []string, caseSensitive bool) map[string]int {
+	scanner.Split(bufio.ScanWords)
+	counts := make(map[string]int, len(terms))
+
+	// Index the terms by their last word so each token only checks the terms
+	// that could end at it
+	type trackedTerm struct {
+		term  string
+		words []string
+	}
+	byLast := make(map[string][]trackedTerm)
+	maxLen := 0
+	for _, term := range terms {
+		// A term listed twice, as often happens across TBX entries, is
+		// indexed once so each occurrence is counted once
+		if _, seen := counts[term]; seen {
+			continue
+		}
+		words := make([]string, 0)
+		for _, f := range strings.Fields(term) {
+			if w := normalizeToken(f, caseSensitive); w != "" {
+				words = append(words, w)
+			}
+		}
+		if len(words) == 0 {
+			continue
+		}
+		counts[term] = 0
+		last := words[len(words)-1]
+		byLast[last] = append(byLast[last], trackedTerm{term: term, words: words})
+		if len(words) > maxLen {
+			maxLen = len(words)
+		}
+	}
+
+	if maxLen == 0 {
+		return counts
+	}
+
+	window := make([]string, 0, maxLen)
+	for scanner.Scan() {
+		word := normalizeToken(scanner.Text(), caseSensitive)
+		if word == "" {
+			continue
+		}
+		if len(window) == maxLen {
+			copy(window, window[1:])
+			window = window[:maxLen-1]
+		}
+		window = append(window, word)
+
+		for _, t := range byLast[word] {
+			if len(t.words) > len(window) {
+				continue
+			}
+			match := true
+			start := len(window) - len(t.words)
+			for i, w := range t.words {
+				if window[start+i] != w {
+					match = false
+					break
+				}
+			}
+			if match {
+				counts[t.term]++
+			}
+		}
+	}
+	return counts
+}
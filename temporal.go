@@ -0,0 +1,274 @@
+package concordance
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TimeMention struct {
+	Text string `json:"text"`
+	// Byte offset of the mention in the text
+	Offset int    `json:"offset"`
+	Kind   string `json:"kind"`
+	// The date the mention refers to, or the start of the period it names.
+	// Zero when the mention could not be resolved
+	Date time.Time `json:"date"`
+	// The unit the date is precise to: "day", "week", "month" or "year"
+	Granularity string `json:"granularity"`
+}
+
+var monthNames = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday,
+	"wednesday": time.Wednesday, "thursday": time.Thursday,
+	"friday": time.Friday, "saturday": time.Saturday,
+}
+
+const (
+	monthPattern   = `(?:jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sept?(?:ember)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)`
+	weekdayPattern = `(?:monday|tuesday|wednesday|thursday|friday|saturday|sunday)`
+)
+
+// Temporal patterns, tried in order. Earlier patterns win where matches overlap
+var temporalPatterns = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"iso", regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`)},
+	{"numeric", regexp.MustCompile(`\b(\d{1,2})[/.](\d{1,2})[/.](\d{4})\b`)},
+	{"month-day-year", regexp.MustCompile(`(?i)\b(` + monthPattern + `)\.? (\d{1,2})(?:st|nd|rd|th)?,? (\d{4})\b`)},
+	{"day-month-year", regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)? (?:of )?(` + monthPattern + `)\.?,? (\d{4})\b`)},
+	{"month-year", regexp.MustCompile(`(?i)\b(` + monthPattern + `)\.?,? (\d{4})\b`)},
+	{"relative-day", regexp.MustCompile(`(?i)\b(yesterday|today|tomorrow)\b`)},
+	{"relative-weekday", regexp.MustCompile(`(?i)\b(last|next|this) (` + weekdayPattern + `)\b`)},
+	{"relative-period", regexp.MustCompile(`(?i)\b(last|next|this) (week|month|year)\b`)},
+	{"ago", regexp.MustCompile(`(?i)\b(\d+|a|an|one|two|three|four|five|six|seven|eight|nine|ten) (day|week|month|year)s? (ago|from now)\b`)},
+	{"year", regexp.MustCompile(`\b(1[5-9]\d{2}|20\d{2})\b`)},
+}
+
+var smallNumbers = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+}
+
+// Finds dates and time expressions in text and returns them in the order they
+// appear. Relative expressions such as "last Tuesday" or "3 days ago" are
+// resolved against anchor; pass the zero time to leave them unresolved.
+// Numeric dates like 04/05/2020 are read day first when dayFirst is true and
+// month first otherwise
+func FindTimeMentions(text string, anchor time.Time, dayFirst bool) []TimeMention {
+	taken := make([]bool, len(text))
+	mentions := make([]TimeMention, 0)
+	for _, p := range temporalPatterns {
+		for _, m := range p.re.FindAllStringSubmatchIndex(text, -1) {
+			if overlaps(taken, m[0], m[1]) {
+				continue
+			}
+			groups := make([]string, 0, len(m)/2-1)
+			for g := 2; g < len(m); g += 2 {
+				groups = append(groups, strings.ToLower(text[m[g]:m[g+1]]))
+			}
+			// The span is taken even if it is not a real date, such as
+			// 2021-13-45, so later patterns do not report a year from it
+			for i := m[0]; i < m[1]; i++ {
+				taken[i] = true
+			}
+			date, granularity := resolveTime(p.kind, groups, anchor, dayFirst)
+			if date.IsZero() && !isRelative(p.kind) {
+				continue
+			}
+			mentions = append(mentions, TimeMention{
+				Text:        text[m[0]:m[1]],
+				Offset:      m[0],
+				Kind:        p.kind,
+				Date:        date,
+				Granularity: granularity,
+			})
+		}
+	}
+	sort.Slice(mentions, func(i, j int) bool {
+		return mentions[i].Offset < mentions[j].Offset
+	})
+	return mentions
+}
+
+func overlaps(taken []bool, start, end int) bool {
+	for i := start; i < end; i++ {
+		if taken[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func isRelative(kind string) bool {
+	return strings.HasPrefix(kind, "relative") || kind == "ago"
+}
+
+// Turns the submatches of a pattern into a date. Returns the zero time if the
+// date is invalid or relative with no anchor
+func resolveTime(kind string, g []string, anchor time.Time, dayFirst bool) (time.Time, string) {
+	switch kind {
+	case "iso":
+		return makeDate(atoi(g[0]), atoi(g[1]), atoi(g[2])), "day"
+	case "numeric":
+		day, month := atoi(g[1]), atoi(g[0])
+		if dayFirst {
+			day, month = month, day
+		}
+		return makeDate(atoi(g[2]), month, day), "day"
+	case "month-day-year":
+		return makeDate(atoi(g[2]), int(monthNames[g[0]]), atoi(g[1])), "day"
+	case "day-month-year":
+		return makeDate(atoi(g[2]), int(monthNames[g[1]]), atoi(g[0])), "day"
+	case "month-year":
+		return makeDate(atoi(g[1]), int(monthNames[g[0]]), 1), "month"
+	case "year":
+		return makeDate(atoi(g[0]), 1, 1), "year"
+	}
+
+	if anchor.IsZero() {
+		return time.Time{}, ""
+	}
+	day := time.Date(anchor.Year(), anchor.Month(), anchor.Day(), 0, 0, 0, 0, time.UTC)
+	switch kind {
+	case "relative-day":
+		return day.AddDate(0, 0, map[string]int{"yesterday": -1, "today": 0, "tomorrow": 1}[g[0]]), "day"
+	case "relative-weekday":
+		diff := int(weekdayNames[g[1]] - day.Weekday())
+		switch g[0] {
+		case "last":
+			if diff >= 0 {
+				diff -= 7
+			}
+		case "next":
+			if diff <= 0 {
+				diff += 7
+			}
+		}
+		return day.AddDate(0, 0, diff), "day"
+	case "relative-period":
+		step := map[string]int{"last": -1, "this": 0, "next": 1}[g[0]]
+		switch g[1] {
+		case "week":
+			monday := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+			return monday.AddDate(0, 0, 7*step), "week"
+		case "month":
+			return time.Date(day.Year(), day.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC), "month"
+		}
+		return time.Date(day.Year()+step, 1, 1, 0, 0, 0, 0, time.UTC), "year"
+	case "ago":
+		n, ok := smallNumbers[g[0]]
+		if !ok {
+			n = atoi(g[0])
+		}
+		if g[2] == "ago" {
+			n = -n
+		}
+		switch g[1] {
+		case "day":
+			return day.AddDate(0, 0, n), "day"
+		case "week":
+			return day.AddDate(0, 0, 7*n), "day"
+		case "month":
+			return day.AddDate(0, n, 0), "month"
+		}
+		return day.AddDate(n, 0, 0), "year"
+	}
+	return time.Time{}, ""
+}
+
+// Returns the date in UTC, or the zero time if it does not exist
+func makeDate(year, month, day int) time.Time {
+	if month < 1 || month > 12 || day < 1 {
+		return time.Time{}
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day {
+		return time.Time{}
+	}
+	return t
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+type TimelineEntry struct {
+	Date        time.Time     `json:"date"`
+	Granularity string        `json:"granularity"`
+	Count       int           `json:"count"`
+	Mentions    []TimeMention `json:"mentions"`
+}
+
+// Groups resolved mentions by the date and granularity they refer to and
+// returns them in date order. Unresolved mentions are left out
+func Timeline(mentions []TimeMention) []TimelineEntry {
+	index := make(map[string]int)
+	timeline := make([]TimelineEntry, 0)
+	for _, m := range mentions {
+		if m.Date.IsZero() {
+			continue
+		}
+		key := m.Date.Format("2006-01-02") + m.Granularity
+		i, ok := index[key]
+		if !ok {
+			i = len(timeline)
+			index[key] = i
+			timeline = append(timeline, TimelineEntry{Date: m.Date, Granularity: m.Granularity})
+		}
+		timeline[i].Count++
+		timeline[i].Mentions = append(timeline[i].Mentions, m)
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Date.Before(timeline[j].Date)
+	})
+	return timeline
+}
+
+// Writes the timeline as an indented JSON array
+func WriteTimelineJSON(w io.Writer, timeline []TimelineEntry) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(timeline)
+}
+
+// Writes the timeline as CSV with one row per mention
+func WriteTimelineCSV(w io.Writer, timeline []TimelineEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"date", "granularity", "text", "offset", "kind"})
+	for _, e := range timeline {
+		for _, m := range e.Mentions {
+			cw.Write([]string{
+				e.Date.Format("2006-01-02"),
+				e.Granularity,
+				m.Text,
+				strconv.Itoa(m.Offset),
+				m.Kind,
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,163 @@
+package concordance
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Groups words into equivalence classes, each named by its first member
+type Thesaurus struct {
+	classes map[string]string
+	members map[string][]string
+}
+
+type ConceptCount struct {
+	Concept string
+	Count   int
+	Members ByCount
+}
+
+// Reads equivalence classes, one per line, from r. A line is either a comma
+// separated list of words, where the first word names the class:
+//
+//	car, automobile, vehicle
+//
+// or a class name followed by a colon and its members:
+//
+//	transport: car, automobile, vehicle
+//
+// Blank lines and lines starting with # are ignored. A word may only belong to
+// one class. When caseSensitive is false words are downcased so that the
+// thesaurus matches counts from a case insensitive WordCount
+func LoadThesaurus(r io.Reader, caseSensitive bool) (*Thesaurus, error) {
+	t := &Thesaurus{
+		classes: make(map[string]string),
+		members: make(map[string][]string),
+	}
+	norm := func(s string) string {
+		s = strings.TrimSpace(s)
+		if !caseSensitive {
+			s = strings.ToLower(s)
+		}
+		return s
+	}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var name string
+		if i := strings.Index(text, ":"); i >= 0 {
+			name = norm(text[:i])
+			text = text[i+1:]
+		}
+		words := make([]string, 0)
+		for _, w := range strings.Split(text, ",") {
+			if w = norm(w); w != "" {
+				words = append(words, w)
+			}
+		}
+		if name == "" && len(words) > 0 {
+			name = words[0]
+		}
+		if name == "" {
+			return nil, fmt.Errorf("concordance: thesaurus line %d: empty class", line)
+		}
+		if _, ok := t.members[name]; ok {
+			return nil, fmt.Errorf("concordance: thesaurus line %d: class %q defined twice", line, name)
+		}
+		t.members[name] = make([]string, 0, len(words))
+		for _, w := range words {
+			if c, ok := t.classes[w]; ok {
+				return nil, fmt.Errorf("concordance: thesaurus line %d: %q is already in class %q", line, w, c)
+			}
+			t.classes[w] = name
+			t.members[name] = append(t.members[name], w)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Returns the class the word belongs to. Words in no class are their own class
+func (t *Thesaurus) Class(word string) string {
+	if c, ok := t.classes[word]; ok {
+		return c
+	}
+	return word
+}
+
+// Returns the members of the named class in the order they were listed
+func (t *Thesaurus) Members(class string) []string {
+	return t.members[class]
+}
+
+// Sums counts per class, returning a map that can be used anywhere word counts
+// are. counts is not modified
+func (t *Thesaurus) Apply(counts map[string]int) map[string]int {
+	grouped := make(map[string]int, len(counts))
+	for w, n := range counts {
+		grouped[t.Class(w)] += n
+	}
+	return grouped
+}
+
+// Sums counts per class while keeping the count of every member that occurs.
+// Concepts are returned most used first
+func (t *Thesaurus) Group(counts map[string]int) []ConceptCount {
+	index := make(map[string]int)
+	concepts := make([]ConceptCount, 0, len(counts))
+	for w, n := range counts {
+		class := t.Class(w)
+		i, ok := index[class]
+		if !ok {
+			i = len(concepts)
+			index[class] = i
+			concepts = append(concepts, ConceptCount{Concept: class})
+		}
+		concepts[i].Count += n
+		concepts[i].Members = append(concepts[i].Members, WordTuple{Word: w, Count: n})
+	}
+	for _, concept := range concepts {
+		m := concept.Members
+		sort.Slice(m, func(i, j int) bool {
+			if m[i].Count != m[j].Count {
+				return m[i].Count > m[j].Count
+			}
+			return m[i].Word < m[j].Word
+		})
+	}
+	sort.Slice(concepts, func(i, j int) bool {
+		if concepts[i].Count != concepts[j].Count {
+			return concepts[i].Count > concepts[j].Count
+		}
+		return concepts[i].Concept < concepts[j].Concept
+	})
+	return concepts
+}
+
+// Returns a new Concordance counting classes instead of words, so MostUsed,
+// Freeze and the exporters all work per concept. The length histogram is
+// built from the class names
+// topWords :: Specifies the maximum length of the MostUsed array. A value <= 0
+// will return them all
+func (c *Concordance) GroupBy(t *Thesaurus, topWords int) *Concordance {
+	g := &Concordance{
+		Counts: t.Apply(c.Counts),
+		Total:  c.Total,
+	}
+	g.Unique = len(g.Counts)
+	g.process()
+	g.TruncateTopWords(topWords)
+
+	return g
+}
@@ -0,0 +1,246 @@
+package concordance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// The change log of a version store, kept next to its snapshots
+const versionLogName = "versions.json"
+
+var (
+	ErrNoChanges       = errors.New("concordance: corpus has not changed since the last version")
+	ErrUnknownVersion  = errors.New("concordance: no such version")
+	ErrNotVersionStore = errors.New("concordance: not a version store")
+)
+
+// One entry of a store's change log
+type Version struct {
+	Number  int       `json:"number"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+	// Names of the documents added, removed and changed since the previous
+	// version
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	// SHA-256 of the text of every document in this version, by name
+	Documents map[string]string `json:"documents"`
+	Total     int               `json:"total"`
+	Words     int               `json:"words"`
+}
+
+// Keeps the history of a corpus as a directory of frozen snapshots, one per
+// version, and a JSON change log. Documents are told apart by name, so a
+// document whose text changes keeps its name and is logged as changed
+type VersionStore struct {
+	dir      string
+	versions []Version
+}
+
+// Opens the version store in dir, creating the directory if it does not exist
+func OpenVersionStore(dir string) (*VersionStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &VersionStore{dir: dir, versions: make([]Version, 0)}
+	b, err := os.ReadFile(filepath.Join(dir, versionLogName))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.versions); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotVersionStore, err)
+	}
+	return s, nil
+}
+
+// Returns the change log, oldest version first
+func (s *VersionStore) Versions() []Version {
+	return append([]Version(nil), s.versions...)
+}
+
+// Returns the most recent version, or false if nothing has been committed
+func (s *VersionStore) Latest() (Version, bool) {
+	if len(s.versions) == 0 {
+		return Version{}, false
+	}
+	return s.versions[len(s.versions)-1], true
+}
+
+// Records the current state of the corpus as a new version with a snapshot of
+// its concordance. Returns ErrNoChanges if no document was added, removed or
+// changed since the previous version
+func (s *VersionStore) Commit(c *Corpus, message string) (Version, error) {
+	v := Version{
+		Number:    len(s.versions) + 1,
+		Time:      time.Now().UTC(),
+		Message:   message,
+		Documents: make(map[string]string, len(c.Documents)),
+	}
+	for _, d := range c.Documents {
+		sum := sha256.Sum256([]byte(d.Text))
+		v.Documents[d.Name] = hex.EncodeToString(sum[:])
+	}
+
+	previous := make(map[string]string)
+	if latest, ok := s.Latest(); ok {
+		previous = latest.Documents
+	}
+	for name, sum := range v.Documents {
+		if old, ok := previous[name]; !ok {
+			v.Added = append(v.Added, name)
+		} else if old != sum {
+			v.Changed = append(v.Changed, name)
+		}
+	}
+	for name := range previous {
+		if _, ok := v.Documents[name]; !ok {
+			v.Removed = append(v.Removed, name)
+		}
+	}
+	if len(s.versions) > 0 && len(v.Added)+len(v.Removed)+len(v.Changed) == 0 {
+		return Version{}, ErrNoChanges
+	}
+	sort.Strings(v.Added)
+	sort.Strings(v.Removed)
+	sort.Strings(v.Changed)
+
+	con := c.Concordance(0)
+	v.Total = con.Total
+	v.Words = len(con.Counts)
+	if err := s.writeSnapshot(v.Number, con.Freeze()); err != nil {
+		return Version{}, err
+	}
+	s.versions = append(s.versions, v)
+	if err := s.writeLog(); err != nil {
+		s.versions = s.versions[:len(s.versions)-1]
+		return Version{}, err
+	}
+	return v, nil
+}
+
+func (s *VersionStore) snapshotPath(n int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("v%06d.frz", n))
+}
+
+func (s *VersionStore) writeSnapshot(n int, f *Frozen) error {
+	file, err := os.Create(s.snapshotPath(n))
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Writes the log to a temporary file and renames it into place, so an
+// interrupted write never leaves a truncated log behind
+func (s *VersionStore) writeLog() error {
+	b, err := json.MarshalIndent(s.versions, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.dir, versionLogName+".tmp")
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, versionLogName))
+}
+
+// Loads the frozen concordance of version n. Use Thaw to get a Concordance
+// back from it
+func (s *VersionStore) Load(n int) (*Frozen, error) {
+	if n < 1 || n > len(s.versions) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, n)
+	}
+	file, err := os.Open(s.snapshotPath(n))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadFrozen(file)
+}
+
+type CountChange struct {
+	Word   string
+	Before int
+	After  int
+}
+
+// The difference between the vocabularies of two versions
+type VocabularyDiff struct {
+	From, To int
+	// Words only in the later version, and only in the earlier one, most used
+	// first
+	Added   ByCount
+	Removed ByCount
+	// Words in both versions whose count changed, largest change first
+	Changed []CountChange
+}
+
+// Compares the vocabularies of versions from and to
+func (s *VersionStore) Diff(from, to int) (*VocabularyDiff, error) {
+	a, err := s.Load(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.Load(to)
+	if err != nil {
+		return nil, err
+	}
+	d := DiffVocabulary(a, b)
+	d.From, d.To = from, to
+	return d, nil
+}
+
+// Compares the vocabularies of two frozen concordances
+func DiffVocabulary(a, b *Frozen) *VocabularyDiff {
+	d := &VocabularyDiff{
+		Added:   make(ByCount, 0),
+		Removed: make(ByCount, 0),
+		Changed: make([]CountChange, 0),
+	}
+	// Both word lists are sorted, so walk them together
+	i, j := 0, 0
+	for i < a.Len() || j < b.Len() {
+		switch {
+		case j == b.Len() || (i < a.Len() && a.Word(i) < b.Word(j)):
+			d.Removed = append(d.Removed, WordTuple{Word: a.Word(i), Count: int(a.counts[i])})
+			i++
+		case i == a.Len() || b.Word(j) < a.Word(i):
+			d.Added = append(d.Added, WordTuple{Word: b.Word(j), Count: int(b.counts[j])})
+			j++
+		default:
+			if a.counts[i] != b.counts[j] {
+				d.Changed = append(d.Changed, CountChange{Word: a.Word(i), Before: int(a.counts[i]), After: int(b.counts[j])})
+			}
+			i++
+			j++
+		}
+	}
+	sort.Stable(sort.Reverse(d.Added))
+	sort.Stable(sort.Reverse(d.Removed))
+	sort.SliceStable(d.Changed, func(x, y int) bool {
+		return abs(d.Changed[x].After-d.Changed[x].Before) > abs(d.Changed[y].After-d.Changed[y].Before)
+	})
+	return d
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,61 @@
+package concordance
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Totals in the style of wc(1), except that Words only counts tokens with
+// something left after ScrubWord, so punctuation on its own is not a word
+type WCCounts struct {
+	Lines int
+	Words int
+	Bytes int
+	Chars int
+}
+
+// Adds o to the counts
+func (c *WCCounts) Add(o WCCounts) {
+	c.Lines += o.Lines
+	c.Words += o.Words
+	c.Bytes += o.Bytes
+	c.Chars += o.Chars
+}
+
+// Counts the lines, words, bytes and characters of r. Lines are counted as
+// newline characters, as wc does, so a last line without one is not counted
+// but its words are. If fn is not nil it is called for every line with its
+// 1-based number, its word count and its text without the newline
+func WordCountLines(r io.Reader, fn func(line, words int, text string)) (WCCounts, error) {
+	var c WCCounts
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			c.Bytes += len(line)
+			c.Chars += utf8.RuneCountInString(line)
+			text := strings.TrimSuffix(line, "\n")
+			if len(text) < len(line) {
+				c.Lines++
+			}
+			words := 0
+			for _, f := range strings.Fields(text) {
+				if ScrubWord(f) != "" {
+					words++
+				}
+			}
+			c.Words += words
+			if fn != nil {
+				fn(n, words, strings.TrimSuffix(text, "\r"))
+			}
+		}
+		if err == io.EOF {
+			return c, nil
+		}
+		if err != nil {
+			return c, err
+		}
+	}
+}